@@ -0,0 +1,16 @@
+/* Characters take on their account's password again */
+ALTER TABLE player_characters ADD COLUMN `password_hash` VARCHAR(255) NOT NULL DEFAULT '' AFTER `username`;
+
+UPDATE
+    player_characters
+INNER JOIN
+    accounts ON accounts.id = player_characters.account_id
+SET
+    player_characters.password_hash = accounts.password_hash;
+
+ALTER TABLE player_characters
+    DROP FOREIGN KEY fk_pc_account,
+    DROP COLUMN `account_id`;
+
+DROP INDEX index_account_username ON accounts;
+DROP TABLE accounts;
@@ -0,0 +1,40 @@
+CREATE TABLE accounts (
+    /* Identity and authentication */
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+    `username` VARCHAR(64) NOT NULL,
+    `email` VARCHAR(255) NULL DEFAULT NULL,
+    `password_hash` VARCHAR(255) NOT NULL,
+
+    /* Timestamps & soft deletion */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` BIGINT DEFAULT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX index_account_username ON accounts(username);
+
+/* Every existing character becomes an account of the same name, keeping its password */
+INSERT INTO
+    accounts(username, password_hash, created_at, deleted_at, deleted_by)
+SELECT
+    username, password_hash, created_at, deleted_at, deleted_by
+FROM
+    player_characters;
+
+ALTER TABLE player_characters ADD COLUMN `account_id` BIGINT NULL AFTER `id`;
+
+UPDATE
+    player_characters
+INNER JOIN
+    accounts ON accounts.username = player_characters.username
+SET
+    player_characters.account_id = accounts.id;
+
+ALTER TABLE player_characters
+    MODIFY `account_id` BIGINT NOT NULL,
+    ADD CONSTRAINT fk_pc_account FOREIGN KEY (account_id) REFERENCES accounts(id),
+    DROP COLUMN `password_hash`;
@@ -0,0 +1,18 @@
+CREATE TABLE bans (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    /* An exact address, a CIDR prefix, or an account/character name */
+    `ban_type` ENUM('host', 'cidr', 'username') NOT NULL,
+    `pattern` VARCHAR(255) NOT NULL,
+    `reason` TEXT,
+    `created_by` VARCHAR(64) NOT NULL,
+
+    /* Timestamps & soft deletion */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` BIGINT DEFAULT NULL,
+
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,22 @@
+CREATE TABLE mail (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `sender_id` BIGINT NOT NULL,
+    `recipient_id` BIGINT NOT NULL,
+    `subject` VARCHAR(80) NOT NULL,
+    `body` TEXT NOT NULL,
+    `read_at` TIMESTAMP NULL DEFAULT NULL,
+
+    /* Timestamps & soft deletion */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` BIGINT DEFAULT NULL,
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (sender_id) REFERENCES player_characters(id),
+    FOREIGN KEY (recipient_id) REFERENCES player_characters(id)
+);
+
+CREATE INDEX index_mail_recipient ON mail(recipient_id);
@@ -0,0 +1,2 @@
+DROP TABLE pc_channel;
+DROP TABLE channels;
@@ -0,0 +1,42 @@
+CREATE TABLE channels (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `name` VARCHAR(32) NOT NULL,
+    `description` VARCHAR(255) DEFAULT NULL,
+    `colour` VARCHAR(8) NOT NULL DEFAULT '{x',
+    `minimum_level` INT NOT NULL DEFAULT 0,
+    `history_length` INT NOT NULL DEFAULT 20,
+    `default_on` BOOLEAN NOT NULL DEFAULT TRUE,
+
+    /* Timestamps & soft deletion */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` BIGINT DEFAULT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX index_channel_name ON channels(name);
+
+INSERT INTO channels(name, description, colour, minimum_level, history_length, default_on) VALUES ('ooc', 'Out-of-character chatter', '{M', 0, 20, 1);
+INSERT INTO channels(name, description, colour, minimum_level, history_length, default_on) VALUES ('newbie', 'Questions and help for new players', '{G', 0, 20, 1);
+
+/* Only channels a player has joined or left against the channel's default are stored */
+CREATE TABLE pc_channel (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `player_character_id` BIGINT NOT NULL,
+    `channel` VARCHAR(32) NOT NULL,
+    `listening` BOOLEAN NOT NULL,
+
+    /* Timestamps */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (player_character_id) REFERENCES player_characters(id)
+);
+
+CREATE UNIQUE INDEX index_pc_channel ON pc_channel(player_character_id, channel);
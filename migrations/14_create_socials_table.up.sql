@@ -0,0 +1,34 @@
+CREATE TABLE socials (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `name` VARCHAR(32) NOT NULL,
+
+    /* $n is replaced with the actor, $N with the target */
+    `char_no_arg` VARCHAR(255) DEFAULT NULL,
+    `others_no_arg` VARCHAR(255) DEFAULT NULL,
+    `char_found` VARCHAR(255) DEFAULT NULL,
+    `others_found` VARCHAR(255) DEFAULT NULL,
+    `vict_found` VARCHAR(255) DEFAULT NULL,
+    `char_auto` VARCHAR(255) DEFAULT NULL,
+    `others_auto` VARCHAR(255) DEFAULT NULL,
+    `not_found` VARCHAR(255) DEFAULT NULL,
+
+    /* Timestamps & soft deletion */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    `deleted_at` TIMESTAMP NULL DEFAULT NULL,
+    `deleted_by` BIGINT DEFAULT NULL,
+
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX index_social_name ON socials(name);
+
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('bow', 'You bow deeply.', '$n bows deeply.', 'You bow before $N.', '$n bows before $N.', '$n bows before you.', 'You bow to yourself; how odd.', '$n bows to nobody in particular.', 'Bow to whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('grin', 'You grin evilly.', '$n grins evilly.', 'You grin evilly at $N.', '$n grins evilly at $N.', '$n grins evilly at you.', 'You grin at yourself.', '$n grins at nobody in particular.', 'Grin at whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('laugh', 'You fall down laughing.', '$n falls down laughing.', 'You laugh at $N.', '$n laughs at $N.', '$n laughs at you.', 'You laugh at yourself.', '$n laughs at nobody in particular.', 'Laugh at whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('nod', 'You nod solemnly.', '$n nods solemnly.', 'You nod to $N.', '$n nods to $N.', '$n nods to you.', 'You nod at yourself.', '$n nods at nobody in particular.', 'Nod to whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('shrug', 'You shrug.', '$n shrugs helplessly.', 'You shrug at $N.', '$n shrugs at $N.', '$n shrugs at you.', 'You shrug to yourself.', '$n shrugs to nobody in particular.', 'Shrug at whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('smile', 'You smile happily.', '$n smiles happily.', 'You smile at $N.', '$n beams a smile at $N.', '$n smiles at you.', 'You smile at yourself.', '$n smiles at nobody in particular.', 'Smile at whom?');
+INSERT INTO socials(name, char_no_arg, others_no_arg, char_found, others_found, vict_found, char_auto, others_auto, not_found) VALUES ('wave', 'You wave.', '$n waves happily.', 'You wave goodbye to $N.', '$n waves goodbye to $N.', '$n waves goodbye to you.', 'You wave to yourself.', '$n waves to nobody in particular.', 'Wave to whom?');
@@ -0,0 +1 @@
+DROP TABLE pc_ignore;
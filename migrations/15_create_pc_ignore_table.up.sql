@@ -0,0 +1,16 @@
+CREATE TABLE pc_ignore (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `player_character_id` BIGINT NOT NULL,
+    `ignored_id` BIGINT NOT NULL,
+
+    /* Timestamps */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (player_character_id) REFERENCES player_characters(id),
+    FOREIGN KEY (ignored_id) REFERENCES player_characters(id)
+);
+
+CREATE UNIQUE INDEX index_pc_ignore ON pc_ignore(player_character_id, ignored_id);
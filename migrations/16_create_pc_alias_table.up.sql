@@ -0,0 +1,16 @@
+CREATE TABLE pc_alias (
+    `id` BIGINT NOT NULL AUTO_INCREMENT,
+
+    `player_character_id` BIGINT NOT NULL,
+    `name` VARCHAR(20) NOT NULL,
+    `expansion` VARCHAR(255) NOT NULL,
+
+    /* Timestamps */
+    `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT NOW() ON UPDATE NOW(),
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (player_character_id) REFERENCES player_characters(id)
+);
+
+CREATE UNIQUE INDEX index_pc_alias ON pc_alias(player_character_id, name);
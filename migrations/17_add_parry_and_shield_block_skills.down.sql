@@ -0,0 +1,3 @@
+DELETE FROM pc_skill_proficiency WHERE skill_id IN (19, 20);
+DELETE FROM job_skill WHERE skill_id IN (19, 20);
+DELETE FROM skills WHERE id IN (19, 20);
@@ -0,0 +1,7 @@
+INSERT INTO skills(id, name, type, intent) VALUES (19, 'parry', 'passive', 'none');
+INSERT INTO skills(id, name, type, intent) VALUES (20, 'shield block', 'passive', 'none');
+
+/* Warriors learn both, thieves learn to parry later on */
+INSERT INTO job_skill(id, job_id, skill_id, level, complexity, cost) VALUES (23, 1, 19, 5, 5, 50);
+INSERT INTO job_skill(id, job_id, skill_id, level, complexity, cost) VALUES (24, 1, 20, 10, 5, 50);
+INSERT INTO job_skill(id, job_id, skill_id, level, complexity, cost) VALUES (25, 2, 19, 15, 8, 50);
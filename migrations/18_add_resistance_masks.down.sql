@@ -0,0 +1,2 @@
+ALTER TABLE `mobiles` DROP `resist`, DROP `immune`, DROP `suscept`;
+ALTER TABLE `races` DROP `resist`, DROP `immune`, DROP `suscept`;
@@ -0,0 +1,13 @@
+/* One bit per damage type, in the order of the DamageType constants */
+ALTER TABLE `races`
+    ADD `resist` INT NOT NULL DEFAULT 0 AFTER `primary_attribute`,
+    ADD `immune` INT NOT NULL DEFAULT 0 AFTER `resist`,
+    ADD `suscept` INT NOT NULL DEFAULT 0 AFTER `immune`;
+
+ALTER TABLE `mobiles`
+    ADD `resist` INT NOT NULL DEFAULT 0 AFTER `stat_lck`,
+    ADD `immune` INT NOT NULL DEFAULT 0 AFTER `resist`,
+    ADD `suscept` INT NOT NULL DEFAULT 0 AFTER `immune`;
+
+/* Dwarves are hardy against poison */
+UPDATE races SET resist = 1 << 7 WHERE id = 3;
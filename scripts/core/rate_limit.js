@@ -0,0 +1,15 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+function onPlayerEnter(ch) {
+    // Builders paste long runs of OLC commands; don't hold them to the flood limit
+    if (ch.level >= Golem.Levels.LevelBuilder) {
+        Golem.rateLimit.exempt(ch, true);
+    }
+}
+
+Golem.registerEventHandler('playerEnter', onPlayerEnter);
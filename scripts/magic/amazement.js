@@ -20,7 +20,7 @@ function spell_amazement(ch) {
 
     const maze = Golem.game.generateDungeon(2, 32, 32);
     if(ch.room.flags & Golem.RoomFlags.ROOM_PLANAR) {
-        ch.room.plane.map.layers[ch.room.z].terrain[ch.room.y][ch.room.x] = Golem.TerrainTypes.TerrainTypeCaveDeepWall1;
+        ch.room.plane.setTerrain(ch.room.x, ch.room.y, ch.room.z, Golem.TerrainTypes.TerrainTypeCaveDeepWall1);
     }
 
     ch.send("{YA crack of lightning sunders the earth before you, revealing a dungeon!{x\r\n");
@@ -32,8 +32,12 @@ function spell_fireball(ch, args) {
 
     target.send('{RYou are enveloped in flames!{x\r\n');
 
+    if (!target.isEqual(ch)) {
+        Golem.game.startCombat(ch, target);
+    }
+
     const amount = ~~(((Math.random() * 30) + 5) * (this.proficiency / 100));
-    Golem.game.damage(ch, target, false, amount, Golem.Combat.DamageTypeExotic);
+    Golem.game.damage(ch, target, false, amount, Golem.Combat.DamageTypeFire);
 }
 
 Golem.registerSpellHandler('fireball', spell_fireball);
@@ -12,6 +12,9 @@ function do_medit(ch, args) {
                 
 {Gmedit <target> save             - {gSave mobile instance properties globally
 {Gmedit <target> flag <flag name> - {gToggle target flag by name
+{Gmedit <target> resist <type>    - {gToggle resistance to a damage type by name
+{Gmedit <target> immune <type>    - {gToggle immunity to a damage type by name
+{Gmedit <target> suscept <type>   - {gToggle susceptibility to a damage type by name
 {Gmedit <target> description      - {gString editor for mobile's description
 
 {WThe following values may be used in a general way with the syntax:
@@ -91,6 +94,36 @@ function do_medit(ch, args) {
             ch.send("Ok.  Disabled character flag " + flag.name + " on " + target.getShortDescription(ch) + ".\r\n");
             return;
 
+        case 'resist':
+        case 'immune':
+        case 'suscept': {
+            if(target.flags & Golem.CharacterFlags.CHAR_IS_PLAYER) {
+                ch.send("Failed: not an NPC.\r\n");
+                return;
+            }
+
+            if (!xxs.length) {
+                ch.send("A damage type argument to toggle is required.\r\nExample: medit troll suscept fire\r\n");
+                return;
+            }
+
+            const damageType = Golem.util.findDamageTypeFlag(xxs);
+            if(!damageType) {
+                ch.send("No such damage type exists.\r\n");
+                return;
+            }
+
+            if(!(target[secondArgument] & damageType.flag)) {
+                target[secondArgument] |= damageType.flag;
+                ch.send("Ok.  Enabled " + secondArgument + " " + damageType.name + " on " + target.getShortDescription(ch) + ".\r\n");
+                return;
+            }
+
+            target[secondArgument] &= ~(damageType.flag);
+            ch.send("Ok.  Disabled " + secondArgument + " " + damageType.name + " on " + target.getShortDescription(ch) + ".\r\n");
+            return;
+        }
+
         case 'short_description':
             if(target.flags & Golem.CharacterFlags.CHAR_IS_PLAYER) {
                 ch.send("Failed: not an NPC.\r\n");
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+function do_sedit(ch, args) {
+    const messageFields = {
+        char_no_arg: 'charNoArg',
+        others_no_arg: 'othersNoArg',
+        char_found: 'charFound',
+        others_found: 'othersFound',
+        vict_found: 'victFound',
+        char_auto: 'charAuto',
+        others_auto: 'othersAuto',
+        not_found: 'notFound',
+    };
+
+    function displayUsage() {
+        ch.send(
+            `{WSocial editor usage:
+
+{Gsedit create <social>              - {gCreate a new, empty social
+{Gsedit <social> show                - {gShow every message of a social
+{Gsedit <social> save                - {gSave the social's messages
+{Gsedit <social> delete              - {gDelete a social
+
+{WThe following values may be used in a general way with the syntax:
+{Gsedit <social> <message> <text>
+{Wto set one of the social's messages; $n is the actor and $N the target.
+
+{GThe following messages are available:{g
+  char_no_arg others_no_arg char_found others_found vict_found
+  char_auto others_auto not_found
+{x`);
+    }
+
+    function displaySocial(social) {
+        let output = `{WSocial: {x${social.name}\r\n`;
+
+        for (const [field, property] of Object.entries(messageFields)) {
+            output += `{G${field.padEnd(14)}{x ${social[property] || '{D(none){x'}\r\n`;
+        }
+
+        ch.send(output);
+    }
+
+    let [firstArgument, xs] = Golem.util.oneArgument(args);
+    let [secondArgument, xxs] = Golem.util.oneArgument(xs);
+
+    if (!args.length) {
+        displayUsage();
+        return;
+    }
+
+    try {
+        if (firstArgument === 'create') {
+            if (!secondArgument.length) {
+                ch.send("Usage: sedit create <social>\r\n");
+                return;
+            }
+
+            const social = Golem.game.createSocial(secondArgument);
+            ch.send("Ok.  Created social " + social.name + "; now set its messages.\r\n");
+            return;
+        }
+
+        const social = Golem.game.findSocial(firstArgument);
+        if (!social) {
+            ch.send("No such social exists.\r\n");
+            return;
+        }
+
+        switch (secondArgument) {
+            case '':
+            case 'show':
+                displaySocial(social);
+                return;
+
+            case 'save':
+                try {
+                    social.sync();
+                } catch (err) {
+                    ch.send("Failed: " + err.toString() + "\r\n");
+                    return;
+                }
+
+                ch.send("Ok.\r\n");
+                return;
+
+            case 'delete':
+                try {
+                    Golem.game.deleteSocial(social);
+                } catch (err) {
+                    ch.send("Failed: " + err.toString() + "\r\n");
+                    return;
+                }
+
+                ch.send("Ok.\r\n");
+                return;
+
+            default:
+                if (!messageFields[secondArgument]) {
+                    displayUsage();
+                    return;
+                }
+
+                social[messageFields[secondArgument]] = xxs;
+                ch.send("Ok.\r\n");
+                return;
+        }
+    } catch (err) {
+        ch.send(err.toString() + "\r\n");
+    }
+}
+
+Golem.registerPlayerCommand('sedit', do_sedit, Golem.Levels.LevelBuilder);
@@ -60,7 +60,7 @@ function do_backstab(ch, args) {
         }
 
         Golem.game.damage(ch, victim, false, victim.health, Golem.Combat.DamageTypeStab);
-        ch.client.delay(2000);
+        ch.setWait(2 * Golem.PulsesPerSecond);
         return;
     }
 
@@ -83,9 +83,11 @@ function do_backstab(ch, args) {
         }
     }
 
+    Golem.game.startCombat(ch, victim);
+
     const amount = ~~(((Math.random() * ch.level) * 5) * (this.proficiency / 100));
     Golem.game.damage(ch, victim, false, amount, Golem.Combat.DamageTypeStab);
-    ch.client.delay(2000);
+    ch.setWait(2 * Golem.PulsesPerSecond);
 }
 
 Golem.registerSkillHandler('backstab', do_backstab);
@@ -38,9 +38,11 @@ function do_bash(ch, args) {
         }
     }
 
+    Golem.game.startCombat(ch, victim);
+
     const amount = ~~(((Math.random() * ch.level) * 1.5) * (this.proficiency / 100));
     Golem.game.damage(ch, victim, false, amount, Golem.Combat.DamageTypeBash);
-    ch.client.delay(2000);
+    ch.setWait(2 * Golem.PulsesPerSecond);
 }
 
 Golem.registerSkillHandler('bash', do_bash);
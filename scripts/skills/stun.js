@@ -38,6 +38,8 @@ function do_stun(ch, args) {
         }
     }
 
+    Golem.game.startCombat(ch, victim);
+
     victim.addEffect(Golem.game.createEffect(
         'paralysis',
         Golem.EffectTypes.EffectTypeAffected,
@@ -61,7 +63,7 @@ function do_stun(ch, args) {
             }
     }));
 
-    ch.client.delay(4000);
+    ch.setWait(4 * Golem.PulsesPerSecond);
 }
 
 Golem.registerSkillHandler('stun', do_stun);
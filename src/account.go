@@ -0,0 +1,451 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+/* Longest email address the accounts table will hold */
+const MaxEmailLength = 255
+
+/*
+ * An account is what a person logs in with; it owns any number of player characters,
+ * all reached through the one password.
+ */
+type Account struct {
+	Game *Game `json:"-"`
+
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+
+	passwordHash string
+}
+
+/* Enough about a character to list it at login without loading the whole thing */
+type AccountCharacter struct {
+	Id    int
+	Name  string
+	Level uint
+	Race  *Race
+	Job   *Job
+}
+
+func (game *Game) findAccount(column string, value interface{}) (*Account, error) {
+	account := &Account{Game: game}
+
+	var email sql.NullString
+
+	row := game.db.QueryRow(fmt.Sprintf(`
+		SELECT
+			id,
+			username,
+			email,
+			password_hash
+		FROM
+			accounts
+		WHERE
+			%s = ?
+		AND
+			deleted_at IS NULL
+	`, column), value)
+
+	err := row.Scan(&account.Id, &account.Name, &email, &account.passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	account.Email = email.String
+	return account, nil
+}
+
+/* Returns nil without an error if no such account exists */
+func (game *Game) FindAccountByName(name string) (*Account, error) {
+	return game.findAccount("username", name)
+}
+
+func (game *Game) FindAccountByID(id int) (*Account, error) {
+	return game.findAccount("id", id)
+}
+
+func (game *Game) FindAccountByCharacterName(name string) (*Account, error) {
+	var accountId int
+
+	row := game.db.QueryRow(`
+		SELECT
+			account_id
+		FROM
+			player_characters
+		WHERE
+			username = ?
+		AND
+			deleted_at IS NULL
+	`, name)
+
+	err := row.Scan(&accountId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return game.FindAccountByID(accountId)
+}
+
+func (game *Game) CreateAccount(name string, passwordHash string) (*Account, error) {
+	result, err := game.db.Exec(`
+		INSERT INTO
+			accounts(username, password_hash)
+		VALUES
+			(?, ?)
+	`, name, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{Game: game, Id: int(id), Name: name, passwordHash: passwordHash}, nil
+}
+
+/* Verify a password, upgrading the stored hash to the current format if it has aged */
+func (account *Account) CheckPassword(password string) bool {
+	ok, rehash := verifyPassword(account.passwordHash, password)
+	if !ok {
+		return false
+	}
+
+	if rehash && account.Id != 0 {
+		err := account.SetPassword(password)
+		if err != nil {
+			log.Printf("Failed to rehash password for account %s: %v\r\n", account.Name, err)
+		} else {
+			log.Printf("Rehashed password for account %s.\r\n", account.Name)
+		}
+	}
+
+	return true
+}
+
+func (account *Account) SetPassword(password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = account.Game.db.Exec(`
+		UPDATE
+			accounts
+		SET
+			password_hash = ?
+		WHERE
+			id = ?
+	`, hash, account.Id)
+	if err != nil {
+		return err
+	}
+
+	account.passwordHash = hash
+	return nil
+}
+
+func (account *Account) SetEmail(email string) error {
+	_, err := account.Game.db.Exec(`
+		UPDATE
+			accounts
+		SET
+			email = ?
+		WHERE
+			id = ?
+	`, email, account.Id)
+	if err != nil {
+		return err
+	}
+
+	account.Email = email
+	return nil
+}
+
+func (account *Account) Characters() ([]*AccountCharacter, error) {
+	rows, err := account.Game.db.Query(`
+		SELECT
+			id,
+			username,
+			level,
+			race_id,
+			job_id
+		FROM
+			player_characters
+		WHERE
+			account_id = ?
+		AND
+			deleted_at IS NULL
+		ORDER BY
+			id
+	`, account.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	characters := make([]*AccountCharacter, 0)
+
+	for rows.Next() {
+		var raceId uint
+		var jobId uint
+
+		character := &AccountCharacter{}
+
+		err = rows.Scan(&character.Id, &character.Name, &character.Level, &raceId, &jobId)
+		if err != nil {
+			return nil, err
+		}
+
+		character.Race = FindRaceByID(raceId)
+		character.Job = FindJobByID(jobId)
+
+		characters = append(characters, character)
+	}
+
+	return characters, nil
+}
+
+/* Whether any character, deleted or not, has already claimed a name */
+func (game *Game) playerNameExists(name string) (bool, error) {
+	var count int
+
+	row := game.db.QueryRow(`
+		SELECT
+			COUNT(*)
+		FROM
+			player_characters
+		WHERE
+			username = ?
+	`, name)
+
+	err := row.Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (character *AccountCharacter) String() string {
+	var race string = "unknown"
+	var job string = "unknown"
+
+	if character.Race != nil {
+		race = character.Race.Name
+	}
+
+	if character.Job != nil {
+		job = character.Job.Name
+	}
+
+	return fmt.Sprintf("%-14s level %-3d %s %s", character.Name, character.Level, race, job)
+}
+
+/* Match a line of input against an account's characters by name or menu number */
+func findAccountCharacter(characters []*AccountCharacter, choice string) *AccountCharacter {
+	index, err := strconv.Atoi(choice)
+	if err == nil {
+		if index < 1 || index > len(characters) {
+			return nil
+		}
+
+		return characters[index-1]
+	}
+
+	for _, character := range characters {
+		if strings.EqualFold(character.Name, choice) {
+			return character
+		}
+	}
+
+	return nil
+}
+
+/* List the logged-in account's characters and ask which to play */
+func (client *Client) showCharacterMenu(output *bytes.Buffer) {
+	client.ConnectionState = ConnectionStateChooseCharacter
+
+	characters, err := client.account.Characters()
+	if err != nil {
+		output.WriteString("Your characters could not be listed right now.\r\n")
+	} else if len(characters) == 0 {
+		output.WriteString(fmt.Sprintf("\r\nThere are no characters on account %s yet.\r\n", client.account.Name))
+	} else {
+		output.WriteString(fmt.Sprintf("\r\nCharacters on account %s:\r\n", client.account.Name))
+
+		for index, character := range characters {
+			output.WriteString(fmt.Sprintf("  %2d) %s\r\n", index+1, character.String()))
+		}
+	}
+
+	output.WriteString("\r\nEnter a character to play, or \"new\" to create one: ")
+}
+
+func (ch *Character) showAccount(account *Account) {
+	var output strings.Builder
+
+	characters, err := account.Characters()
+	if err != nil {
+		ch.Send("A strange force prevents you from viewing that account.\r\n")
+		return
+	}
+
+	email := account.Email
+	if email == "" {
+		email = "none"
+	}
+
+	output.WriteString(fmt.Sprintf("{WAccount:{x %s\r\n", account.Name))
+	output.WriteString(fmt.Sprintf("{WEmail:{x   %s\r\n", email))
+	output.WriteString("{WCharacters:{x\r\n")
+
+	for _, character := range characters {
+		output.WriteString(fmt.Sprintf("  %s\r\n", character.String()))
+	}
+
+	ch.Send(output.String())
+}
+
+func do_account(ch *Character, arguments string) {
+	if ch.Client == nil {
+		return
+	}
+
+	account, err := ch.Game.FindAccountByID(ch.AccountId)
+	if err != nil || account == nil {
+		ch.Send("A strange force prevents you from accessing your account.\r\n")
+		return
+	}
+
+	if len(arguments) < 1 {
+		ch.showAccount(account)
+
+		ch.Send("\r\n{WAccount management:\r\n" +
+			"{Gemail <address>                 - {gchange the account's email address\r\n" +
+			"{Gpassword <current> <new>        - {gchange the account's password{x\r\n")
+
+		if ch.isAdmin() {
+			ch.Send("{G<character>                     - {gshow the account a character belongs to{x\r\n")
+		}
+
+		return
+	}
+
+	firstArgument, arguments := OneArgument(arguments)
+
+	switch strings.ToLower(firstArgument) {
+	case "email":
+		email := strings.TrimSpace(arguments)
+		if email == "" || len(email) > MaxEmailLength || strings.ContainsAny(email, " \t") || !strings.Contains(email, "@") {
+			ch.Send("Please provide a valid email address.\r\n")
+			return
+		}
+
+		err = account.SetEmail(email)
+		if err != nil {
+			ch.Send("A strange force prevents you from changing your email address.\r\n")
+			return
+		}
+
+		ch.Send(fmt.Sprintf("Email address for account %s changed to %s.\r\n", account.Name, email))
+
+	case "password":
+		do_password(ch, arguments)
+
+	default:
+		if !ch.isAdmin() {
+			ch.Send("Unknown account command.\r\n")
+			return
+		}
+
+		targetAccount, err := ch.Game.FindAccountByCharacterName(strings.Title(strings.ToLower(firstArgument)))
+		if err != nil || targetAccount == nil {
+			ch.Send("No player by that name exists.\r\n")
+			return
+		}
+
+		ch.showAccount(targetAccount)
+	}
+}
+
+/*
+ * Passwords are taken as typed, like they are at login: OneArgument would lowercase them
+ * and treat quotes as delimiters.
+ */
+func passwordArguments(arguments string) (string, string) {
+	fields := strings.Fields(arguments)
+
+	switch len(fields) {
+	case 0:
+		return "", ""
+
+	case 1:
+		return fields[0], ""
+	}
+
+	return fields[0], fields[1]
+}
+
+func do_password(ch *Character, arguments string) {
+	if ch.Client == nil {
+		return
+	}
+
+	current, replacement := passwordArguments(arguments)
+
+	if current == "" || replacement == "" {
+		ch.Send("Usage: password <current> <new>\r\n")
+		return
+	}
+
+	account, err := ch.Game.FindAccountByID(ch.AccountId)
+	if err != nil || account == nil {
+		ch.Send("A strange force prevents you from accessing your account.\r\n")
+		return
+	}
+
+	if !account.CheckPassword(current) {
+		ch.SetWait(3 * PulsesPerSecond)
+		ch.Send("Wrong password.\r\n")
+		return
+	}
+
+	if len(replacement) < MinimumPasswordLength {
+		ch.Send(fmt.Sprintf("Your new password must be at least %d characters long.\r\n", MinimumPasswordLength))
+		return
+	}
+
+	err = account.SetPassword(replacement)
+	if err != nil {
+		ch.Send("A strange force prevents you from changing your password.\r\n")
+		return
+	}
+
+	ch.Send(fmt.Sprintf("Password for account %s changed.\r\n", account.Name))
+}
@@ -13,9 +13,21 @@ import (
 	"time"
 )
 
+/* Tells held for a player who is AFK or linkdead */
+const MaxQueuedTells = 20
+
+type QueuedTell struct {
+	from     string
+	message  string
+	queuedAt time.Time
+}
+
 type AwayFromKeyboard struct {
 	startedAt time.Time
 	message   string
+
+	/* Set by the idle timer rather than the player, so cleared by any input */
+	automatic bool
 }
 
 func do_afk(ch *Character, arguments string) {
@@ -28,6 +40,7 @@ func do_afk(ch *Character, arguments string) {
 	if ch.Afk != nil {
 		ch.Send("{GYou have returned from AFK.{x\r\n")
 		ch.Afk = nil
+		ch.replayQueuedTells()
 		return
 	}
 
@@ -49,6 +62,7 @@ func do_say(ch *Character, arguments string) {
 	}
 
 	ch.Send(fmt.Sprintf("{CYou say \"%s{C\"{x\r\n", arguments))
+	ch.sendGMCPChannel("say", ch, fmt.Sprintf("You say \"%s\"", arguments))
 
 	buf.WriteString(fmt.Sprintf("\r\n{C%s says \"%s{C\"{x\r\n", ch.Name, arguments))
 	output := buf.String()
@@ -57,29 +71,129 @@ func do_say(ch *Character, arguments string) {
 		for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
 			rch := iter.Value.(*Character)
 
-			if rch != ch {
+			if rch != ch && !rch.IsIgnoring(ch) {
 				rch.Send(output)
+				rch.sendGMCPChannel("say", ch, output)
 			}
 		}
 	}
 }
 
 func do_ooc(ch *Character, arguments string) {
+	ch.useChannel("ooc", arguments)
+}
+
+/* Find a player in the world by name, whether or not they are connected */
+func (game *Game) findPlayerInWorld(name string) *Character {
+	for iter := game.Characters.Head; iter != nil; iter = iter.Next {
+		ch := iter.Value.(*Character)
+
+		if ch.Flags&CHAR_IS_PLAYER != 0 && strings.EqualFold(ch.Name, name) {
+			return ch
+		}
+	}
+
+	return nil
+}
+
+/* Hand over any tells which arrived while the player was away */
+func (ch *Character) replayQueuedTells() {
+	if len(ch.queuedTells) == 0 {
+		return
+	}
+
 	var buf strings.Builder
 
-	if len(arguments) == 0 {
-		ch.Send("{MWhat message do you wish to globally send out-of-character?{x\r\n")
+	buf.WriteString(fmt.Sprintf("{YYou received %d tells while you were away:{x\r\n", len(ch.queuedTells)))
+
+	for _, tell := range ch.queuedTells {
+		buf.WriteString(fmt.Sprintf("{Y[%s] %s tells you '%s{Y'{x\r\n", tell.queuedAt.Format("15:04"), tell.from, tell.message))
+	}
+
+	ch.queuedTells = nil
+	ch.Send(buf.String())
+}
+
+func (ch *Character) tell(target *Character, message string) {
+	if target == ch {
+		ch.Send("{YTalking to yourself again?{x\r\n")
 		return
 	}
 
-	buf.WriteString(fmt.Sprintf("\r\n{M[OOC] %s: %s{x\r\n", ch.Name, arguments))
-	output := buf.String()
+	if target.IsIgnoring(ch) {
+		ch.Send(fmt.Sprintf("{Y%s is ignoring you.{x\r\n", target.Name))
+		return
+	}
+
+	target.replyTo = ch.Name
 
-	for client := range ch.Game.clients {
-		if client.Character != nil && client.ConnectionState == ConnectionStatePlaying {
-			client.Character.Send(output)
+	if target.isLinkdead() || target.Afk != nil {
+		if len(target.queuedTells) >= MaxQueuedTells {
+			ch.Send(fmt.Sprintf("{Y%s is away and cannot hold any more tells right now.{x\r\n", target.Name))
+			return
+		}
+
+		target.queuedTells = append(target.queuedTells, QueuedTell{
+			from:     ch.Name,
+			message:  message,
+			queuedAt: time.Now(),
+		})
+
+		if target.isLinkdead() {
+			ch.Send(fmt.Sprintf("{Y%s has lost their link; your tell will be delivered when they return.{x\r\n", target.Name))
+		} else {
+			ch.Send(fmt.Sprintf("{Y%s is AFK (%s); your tell will be delivered when they return.{x\r\n", target.Name, target.Afk.message))
 		}
+
+		return
+	}
+
+	ch.Send(fmt.Sprintf("{YYou tell %s '%s{Y'{x\r\n", target.Name, message))
+	ch.sendGMCPChannel("tell", ch, fmt.Sprintf("You tell %s '%s'", target.Name, message))
+
+	output := fmt.Sprintf("\r\n{Y%s tells you '%s{Y'{x\r\n", ch.Name, message)
+	target.Send(output)
+	target.sendGMCPChannel("tell", ch, output)
+}
+
+func do_tell(ch *Character, arguments string) {
+	name, message := OneArgument(arguments)
+	message = strings.TrimSpace(message)
+
+	if name == "" || message == "" {
+		ch.Send("{YTell whom what?{x\r\n")
+		return
 	}
+
+	target := ch.Game.findPlayerInWorld(name)
+	if target == nil {
+		ch.Send("{YThey aren't here.{x\r\n")
+		return
+	}
+
+	ch.tell(target, message)
+}
+
+func do_reply(ch *Character, arguments string) {
+	message := strings.TrimSpace(arguments)
+
+	if ch.replyTo == "" {
+		ch.Send("{YNobody has sent you a tell to reply to.{x\r\n")
+		return
+	}
+
+	if message == "" {
+		ch.Send("{YReply with what?{x\r\n")
+		return
+	}
+
+	target := ch.Game.findPlayerInWorld(ch.replyTo)
+	if target == nil {
+		ch.Send(fmt.Sprintf("{Y%s is no longer here.{x\r\n", ch.replyTo))
+		return
+	}
+
+	ch.tell(target, message)
 }
 
 func do_save(ch *Character, arguments string) {
@@ -92,8 +206,9 @@ func do_save(ch *Character, arguments string) {
 	ch.Send("Saved.\r\n")
 }
 
-func do_quit(ch *Character, arguments string) {
-	ch.Save()
+/* Take a player and their belongings out of the world, announcing their departure */
+func (ch *Character) leaveWorld(announcement string) {
+	ch.Game.stopFighting(ch)
 
 	/* If this character is leading a group, disband it */
 	if ch.Group != nil {
@@ -108,8 +223,7 @@ func do_quit(ch *Character, arguments string) {
 		ch.Room.removeCharacter(ch)
 	}
 
-	out := fmt.Sprintf("{W%s has quit the game.{x\r\n", ch.Name)
-	ch.Game.broadcast(out, func(character *Character) bool {
+	ch.Game.broadcast(announcement, func(character *Character) bool {
 		return character != ch
 	})
 
@@ -127,6 +241,11 @@ func do_quit(ch *Character, arguments string) {
 
 		ch.Game.Objects.Remove(obj)
 	}
+}
+
+func do_quit(ch *Character, arguments string) {
+	ch.Save()
+	ch.leaveWorld(fmt.Sprintf("{W%s has quit the game.{x\r\n", ch.Name))
 
 	ch.Client.ConnectionState = ConnectionStateNone
 	ch.Send("{WLeaving for the real world...{x\r\n")
@@ -232,6 +351,11 @@ func do_group(ch *Character, arguments string) {
 		ch.Group.Remove(target)
 		target.Leader = nil
 	} else {
+		if target.IsIgnoring(ch) {
+			ch.Send(fmt.Sprintf("{W%s{W is ignoring you.{x\r\n", target.GetShortDescriptionUpper(ch)))
+			return
+		}
+
 		ch.Send(fmt.Sprintf("{W%s{W joins your group.{x\r\n", target.GetShortDescriptionUpper(ch)))
 		target.Send(fmt.Sprintf("{WYou join %s{W's group.{x\r\n", ch.GetShortDescription(target)))
 
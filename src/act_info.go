@@ -89,9 +89,6 @@ func (ch *Character) examineCharacter(other *Character) {
 
 /* List all commands available to the player in rows of 7 items. */
 func do_help(ch *Character, arguments string) {
-	var buf strings.Builder
-	var index int = 0
-
 	var commands []string = []string{}
 
 	for _, command := range CommandTable {
@@ -109,21 +106,42 @@ func do_help(ch *Character, arguments string) {
 	}
 
 	sort.Strings(commands)
+	ch.sendCommandColumns(commands)
+}
+
+/* List the commands available to the player in the order abbreviations resolve to them */
+func do_commands(ch *Character, arguments string) {
+	ch.Send("{WAbbreviations match the first command listed here that they begin.{x\r\n")
+	ch.sendCommandColumns(commandOrder())
+}
+
+func (ch *Character) sendCommandColumns(commands []string) {
+	var buf strings.Builder
+	var index int = 0
+
+	/* As many 11-character columns as will fit, up to the classic seven */
+	columns := ch.screenWidth() / 11
+	if columns > 7 {
+		columns = 7
+	} else if columns < 1 {
+		columns = 1
+	}
 
 	for _, command := range commands {
-		if ch.Level <= CommandTable[command].MinimumLevel || CommandTable[command].Hidden {
+		val, ok := CommandTable[command]
+		if ok && (ch.Level < val.MinimumLevel || val.Hidden) {
 			continue
 		}
 
 		buf.WriteString(fmt.Sprintf("%-10s ", command))
 		index++
 
-		if index%7 == 0 {
+		if index%columns == 0 {
 			buf.WriteString("\r\n")
 		}
 	}
 
-	if index%7 != 0 {
+	if index%columns != 0 {
 		buf.WriteString("\r\n")
 	}
 
@@ -153,8 +171,9 @@ func do_scan(ch *Character, arguments string) {
 		ch.Send("{CYou squint intently and scan the horizon in all directions:{x\r\n")
 
 		// Query for characters within the do_look camera viewport rect
-		var cameraWidth float64 = 48
-		var cameraHeight float64 = 18
+		viewportWidth, viewportHeight := ch.planeMapViewport()
+		var cameraWidth float64 = float64(viewportWidth)
+		var cameraHeight float64 = float64(viewportHeight)
 		var cameraRange int = 9
 		var output strings.Builder
 
@@ -223,6 +242,12 @@ func do_affect(ch *Character, arguments string) {
 				int(math.Max(0, time.Until(fx.CreatedAt.Add(time.Duration(fx.Duration)*time.Second)).Seconds()))))
 		case EffectTypeAffected:
 			buf.WriteString(fmt.Sprintf("{Y* {MLevel {Y%d '%s' {Mspell for another {Y%d{M seconds.{x\r\n", fx.Level, GetAffectedFlagName(fx.Bits), int(math.Max(0, time.Until(fx.CreatedAt.Add(time.Duration(fx.Duration)*time.Second)).Seconds()))))
+		case EffectTypeImmunity:
+			buf.WriteString(fmt.Sprintf("{Y* {MLevel {Y%d '%s' {Mspell granting immunity to {Y%s{M for another {Y%d{M seconds.{x\r\n",
+				fx.Level,
+				fx.Name,
+				damageTypeMaskNames(fx.Bits),
+				int(math.Max(0, time.Until(fx.CreatedAt.Add(time.Duration(fx.Duration)*time.Second)).Seconds()))))
 		}
 	}
 
@@ -297,13 +322,27 @@ func do_who(ch *Character, arguments string) {
 		}
 	}
 
+	for iter := ch.Game.Characters.Head; iter != nil; iter = iter.Next {
+		character := iter.Value.(*Character)
+
+		if character.isLinkdead() {
+			characters = append(characters, character)
+		}
+	}
+
 	sort.Slice(characters, func(i int, j int) bool {
 		return characters[i].Level > characters[j].Level
 	})
 
+	narrow := ch.screenWidth() < 64
+
 	for _, character := range characters {
 		var flagsString strings.Builder
 
+		if character.isLinkdead() {
+			flagsString.WriteString("{D[LINKDEAD]{x ")
+		}
+
 		if character.Afk != nil {
 			afkMinutes := int(time.Since(character.Afk.startedAt).Minutes())
 
@@ -344,7 +383,19 @@ func do_who(ch *Character, arguments string) {
 			extrasString.WriteString("{M[<FIGHTING>]{x ")
 		}
 
-		if character.Level >= LevelHero {
+		if ch.isAdmin() && character.Client != nil && character.Client.isTLS() {
+			extrasString.WriteString("{G[TLS]{x ")
+		}
+
+		if narrow {
+			/* Drop the class and location columns on small terminals */
+			buf.WriteString(fmt.Sprintf("[%3d] %s %s(%s) %s\r\n",
+				character.Level,
+				character.Name,
+				flagsString.String(),
+				character.Race.DisplayName,
+				extrasString.String()))
+		} else if character.Level >= LevelHero {
 			buf.WriteString(fmt.Sprintf("[%-15s][%-7s] %s %s(%s) %s\r\n",
 				jobDisplay,
 				locationString,
@@ -162,6 +162,11 @@ func (ch *Character) move(direction uint, follow bool) bool {
 		return false
 	}
 
+	if ch.Position < PositionStanding {
+		ch.Send(positionRefusal(ch.Position))
+		return false
+	}
+
 	if ch.Room == nil {
 		ch.Send("{RAlas, you cannot go that way.{x\r\n")
 		return false
@@ -219,7 +224,7 @@ func (ch *Character) move(direction uint, follow bool) bool {
 	for iter := from.Characters.Head; iter != nil; iter = iter.Next {
 		character := iter.Value.(*Character)
 
-		if character.Following == ch {
+		if character.Following == ch && character.Position == PositionStanding {
 			character.Send(fmt.Sprintf("{WYou follow %s{W.{x\r\n", ch.GetShortDescription(character)))
 			character.move(direction, true)
 		}
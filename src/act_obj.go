@@ -208,6 +208,7 @@ func (ch *Character) detachEquipment(obj *ObjectInstance) bool {
 	}
 
 	obj.WearLocation = -1
+	ch.markDirty()
 	return true
 }
 
@@ -217,6 +218,7 @@ func (ch *Character) attachEquipment(obj *ObjectInstance, wearLocation int) bool
 	}
 
 	obj.WearLocation = wearLocation
+	ch.markDirty()
 	return true
 }
 
@@ -799,6 +801,7 @@ func do_take(ch *Character, arguments string) {
 					ch.AddObject(takingObj)
 				} else {
 					ch.Gold = ch.Gold + takingObj.Value0
+					ch.markDirty()
 					ch.Game.Objects.Remove(takingObj)
 				}
 
@@ -844,6 +847,7 @@ func do_take(ch *Character, arguments string) {
 				ch.AddObject(takingObj)
 			} else {
 				ch.Gold = ch.Gold + takingObj.Value0
+				ch.markDirty()
 				ch.Game.Objects.Remove(takingObj)
 			}
 
@@ -894,6 +898,7 @@ func do_take(ch *Character, arguments string) {
 				ch.AddObject(found)
 			} else {
 				ch.Gold = ch.Gold + found.Value0
+				ch.markDirty()
 				ch.Game.Objects.Remove(found)
 			}
 
@@ -951,6 +956,7 @@ func do_take(ch *Character, arguments string) {
 		ch.AddObject(found)
 	} else {
 		ch.Gold = ch.Gold + found.Value0
+		ch.markDirty()
 		ch.Game.Objects.Remove(found)
 	}
 
@@ -996,6 +1002,11 @@ func do_give(ch *Character, arguments string) {
 			return
 		}
 
+		if target.IsIgnoring(ch) {
+			ch.Send(fmt.Sprintf("%s{x doesn't want anything from you.\r\n", target.GetShortDescriptionUpper(ch)))
+			return
+		}
+
 		if amount <= 0 {
 			ch.Send("Invalid amount.\r\n")
 			return
@@ -1011,6 +1022,8 @@ func do_give(ch *Character, arguments string) {
 
 		ch.Gold -= amount
 		target.Gold += amount
+		ch.markDirty()
+		target.markDirty()
 
 		ch.Send(fmt.Sprintf("You give %s{x to %s{x.\r\n", goldRepresentation.GetShortDescription(ch), target.GetShortDescription(ch)))
 		target.Send(fmt.Sprintf("%s{x gives you %s{x.\r\n", ch.GetShortDescriptionUpper(target), goldRepresentation.GetShortDescription(target)))
@@ -1045,6 +1058,11 @@ func do_give(ch *Character, arguments string) {
 		return
 	}
 
+	if target.IsIgnoring(ch) {
+		ch.Send(fmt.Sprintf("%s{x doesn't want anything from you.\r\n", target.GetShortDescriptionUpper(ch)))
+		return
+	}
+
 	if ch.Flags&CHAR_IS_PLAYER != 0 {
 		err := ch.DetachObject(found)
 		if err != nil {
@@ -1142,6 +1160,7 @@ func do_drop(ch *Character, arguments string) {
 		}
 
 		ch.Gold -= amount
+		ch.markDirty()
 
 		var found *ObjectInstance = nil
 
@@ -9,6 +9,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -105,6 +106,7 @@ func do_purge(ch *Character, arguments string) {
 			continue
 		}
 
+		ch.Game.stopFighting(rch)
 		ch.Room.Characters.Remove(rch)
 	}
 
@@ -136,28 +138,74 @@ func do_peace(ch *Character, arguments string) {
 		rch := iter.Value.(*Character)
 
 		rch.Flags &= ^CHAR_AGGRESSIVE
-		rch.Fighting = nil
-		rch.Combat = nil
+		ch.Game.stopFighting(rch)
 	}
 
 	ch.Send("Ok.\r\n")
 }
 
+func do_fights(ch *Character, arguments string) {
+	var output strings.Builder
+
+	if ch.Game.Fights.Count == 0 {
+		ch.Send("There are no fights going on.\r\n")
+		return
+	}
+
+	index := 0
+
+	for iter := ch.Game.Fights.Head; iter != nil; iter = iter.Next {
+		combat := iter.Value.(*Combat)
+		index++
+
+		location := "nowhere"
+		if combat.Room != nil {
+			location = fmt.Sprintf("[%d] %s", combat.Room.Id, combat.Room.Name)
+		}
+
+		output.WriteString(fmt.Sprintf("{Y#%-3d %s{Y, for %s:{x\r\n", index, location, time.Since(combat.StartedAt).Round(time.Second)))
+
+		for _, participant := range combat.Participants {
+			target := "nobody"
+			if participant.Fighting != nil {
+				target = participant.Fighting.GetShortDescription(ch)
+			}
+
+			output.WriteString(fmt.Sprintf("     %-24s %5d/%5dhp  fighting %s{x\r\n",
+				participant.GetShortDescriptionUpper(ch),
+				participant.Health,
+				participant.MaxHealth,
+				target))
+		}
+	}
+
+	ch.Send(output.String())
+}
+
 func do_shutdown(ch *Character, arguments string) {
 	if ch.Client != nil {
 		ch.Game.shutdownRequest <- true
 	}
 }
 
-func do_wiznet(ch *Character, arguments string) {
-	if ch.Wiznet {
-		ch.Wiznet = false
-		ch.Send("Wiznet disabled.\r\n")
+func do_copyover(ch *Character, arguments string) {
+	if ch.Client == nil {
 		return
 	}
 
-	ch.Wiznet = true
-	ch.Send("Wiznet enabled.\r\n")
+	out := fmt.Sprintf("%s has initiated a copyover.\r\n", ch.Name)
+	log.Print(out)
+	ch.Game.broadcast(out, WiznetBroadcastFilter)
+
+	err := ch.Game.copyover()
+	if err != nil {
+		log.Printf("Copyover failed: %v\r\n", err)
+		ch.Send(fmt.Sprintf("{RCopyover failed: %v{x\r\n", err))
+	}
+}
+
+func do_wiznet(ch *Character, arguments string) {
+	ch.useChannel(WiznetChannelName, arguments)
 }
 
 func do_webhook(ch *Character, arguments string) {
@@ -0,0 +1,305 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const MaxAliases = 50
+const MaxAliasNameLength = 20
+const MaxAliasExpansionLength = 255
+
+/* How deeply aliases may expand into other aliases, and how many commands one line may become */
+const MaxAliasDepth = 5
+const MaxAliasCommands = 20
+
+var ErrAliasTooDeep = errors.New("aliases nest too deeply")
+var ErrAliasTooLong = errors.New("alias expands to too many commands")
+
+/*
+ * Substitute a line's arguments into an alias body: $1 through $n are single words,
+ * $* is every argument and $$ a literal dollar sign.  A body with no substitutions
+ * has the arguments appended instead, so "k" aliased to "kill" still takes a target.
+ */
+func substituteAliasArguments(body string, arguments string) string {
+	var buf strings.Builder
+	var substituted bool = false
+
+	words := strings.Fields(arguments)
+
+	for index := 0; index < len(body); index++ {
+		if body[index] != '$' || index+1 >= len(body) {
+			buf.WriteByte(body[index])
+			continue
+		}
+
+		next := body[index+1]
+
+		switch {
+		case next == '*':
+			buf.WriteString(strings.TrimSpace(arguments))
+			substituted = true
+			index++
+
+		case next == '$':
+			buf.WriteByte('$')
+			index++
+
+		case next >= '1' && next <= '9':
+			end := index + 1
+			for end < len(body) && body[end] >= '0' && body[end] <= '9' {
+				end++
+			}
+
+			position, _ := strconv.Atoi(body[index+1 : end])
+			if position <= len(words) {
+				buf.WriteString(words[position-1])
+			}
+
+			substituted = true
+			index = end - 1
+
+		default:
+			buf.WriteByte('$')
+		}
+	}
+
+	if !substituted && strings.TrimSpace(arguments) != "" {
+		buf.WriteString(" ")
+		buf.WriteString(strings.TrimSpace(arguments))
+	}
+
+	return buf.String()
+}
+
+/*
+ * Expand a line of input through a set of aliases into the commands it stands for.
+ * An alias used again within its own expansion is taken as the command of that name,
+ * so "look" can be aliased to "look;exits" without looping.
+ */
+func expandAliases(aliases map[string]string, input string) ([]string, error) {
+	commands := make([]string, 0)
+
+	err := expandAlias(aliases, input, make(map[string]bool), 0, &commands)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
+
+func expandAlias(aliases map[string]string, input string, expanding map[string]bool, depth int, commands *[]string) error {
+	input = strings.TrimSpace(input)
+
+	name, arguments := input, ""
+	if index := strings.IndexAny(input, " \t"); index >= 0 {
+		name, arguments = input[:index], input[index+1:]
+	}
+
+	name = strings.ToLower(name)
+
+	body, ok := aliases[name]
+	if !ok || expanding[name] {
+		if len(*commands) >= MaxAliasCommands {
+			return ErrAliasTooLong
+		}
+
+		*commands = append(*commands, input)
+		return nil
+	}
+
+	if depth >= MaxAliasDepth {
+		return ErrAliasTooDeep
+	}
+
+	expanding[name] = true
+	defer delete(expanding, name)
+
+	for _, line := range strings.Split(substituteAliasArguments(body, arguments), ";") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		err := expandAlias(aliases, line, expanding, depth+1, commands)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ch *Character) LoadPlayerAliases() error {
+	rows, err := ch.Game.db.Query(`
+		SELECT
+			name,
+			expansion
+		FROM
+			pc_alias
+		WHERE
+			player_character_id = ?
+	`, ch.Id)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var expansion string
+
+		err := rows.Scan(&name, &expansion)
+		if err != nil {
+			return err
+		}
+
+		ch.aliases[name] = expansion
+	}
+
+	return nil
+}
+
+func (ch *Character) setAlias(name string, expansion string) error {
+	_, err := ch.Game.db.Exec(`
+		INSERT INTO
+			pc_alias(player_character_id, name, expansion)
+		VALUES
+			(?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			expansion = VALUES(expansion)
+	`, ch.Id, name, expansion)
+	if err != nil {
+		return err
+	}
+
+	ch.aliases[name] = expansion
+	return nil
+}
+
+func (ch *Character) removeAlias(name string) error {
+	_, err := ch.Game.db.Exec(`
+		DELETE FROM
+			pc_alias
+		WHERE
+			player_character_id = ?
+		AND
+			name = ?
+	`, ch.Id, name)
+	if err != nil {
+		return err
+	}
+
+	delete(ch.aliases, name)
+	return nil
+}
+
+func do_alias(ch *Character, arguments string) {
+	if ch.Flags&CHAR_IS_PLAYER == 0 {
+		return
+	}
+
+	name, expansion := OneArgument(arguments)
+	expansion = strings.TrimSpace(expansion)
+
+	if name == "" {
+		if len(ch.aliases) == 0 {
+			ch.Send("You have no aliases defined.\r\n")
+			return
+		}
+
+		names := make([]string, 0, len(ch.aliases))
+		for alias := range ch.aliases {
+			names = append(names, alias)
+		}
+
+		sort.Strings(names)
+
+		var output strings.Builder
+
+		output.WriteString("{WYour aliases:{x\r\n")
+		for _, alias := range names {
+			output.WriteString(fmt.Sprintf("{G%-12s{x %s\r\n", alias, ch.aliases[alias]))
+		}
+
+		ch.Send(output.String())
+		return
+	}
+
+	if expansion == "" {
+		body, ok := ch.aliases[name]
+		if !ok {
+			ch.Send("You have no alias by that name.\r\n")
+			return
+		}
+
+		ch.Send(fmt.Sprintf("{G%s{x is aliased to: %s\r\n", name, body))
+		return
+	}
+
+	if name == "alias" || name == "unalias" {
+		ch.Send("You can't redefine that command.\r\n")
+		return
+	}
+
+	if len(name) > MaxAliasNameLength || strings.ContainsAny(name, ";$") {
+		ch.Send(fmt.Sprintf("Alias names must be under %d characters and can't contain ';' or '$'.\r\n", MaxAliasNameLength))
+		return
+	}
+
+	if len(expansion) > MaxAliasExpansionLength {
+		ch.Send(fmt.Sprintf("Please keep aliases under %d characters.\r\n", MaxAliasExpansionLength))
+		return
+	}
+
+	if _, ok := ch.aliases[name]; !ok && len(ch.aliases) >= MaxAliases {
+		ch.Send(fmt.Sprintf("You can't have more than %d aliases.\r\n", MaxAliases))
+		return
+	}
+
+	err := ch.setAlias(name, expansion)
+	if err != nil {
+		log.Printf("Failed to save alias %s for %s: %v.\r\n", name, ch.Name, err)
+		ch.Send("A strange force prevents you from saving that alias.\r\n")
+		return
+	}
+
+	ch.Send(fmt.Sprintf("{G%s{x is now aliased to: %s\r\n", name, expansion))
+}
+
+func do_unalias(ch *Character, arguments string) {
+	if ch.Flags&CHAR_IS_PLAYER == 0 {
+		return
+	}
+
+	name, _ := OneArgument(arguments)
+	if name == "" {
+		ch.Send("Remove which alias?\r\n")
+		return
+	}
+
+	if _, ok := ch.aliases[name]; !ok {
+		ch.Send("You have no alias by that name.\r\n")
+		return
+	}
+
+	err := ch.removeAlias(name)
+	if err != nil {
+		log.Printf("Failed to remove alias %s for %s: %v.\r\n", name, ch.Name, err)
+		ch.Send("A strange force prevents you from removing that alias.\r\n")
+		return
+	}
+
+	ch.Send(fmt.Sprintf("Alias %s removed.\r\n", name))
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type aliasSubstitutionTest struct {
+	body, arguments, expected string
+}
+
+var aliasSubstitutionTests = []aliasSubstitutionTest{
+	{"kill", "orc", "kill orc"},
+	{"kill", "", "kill"},
+	{"cast 'fireball' $1", "orc guard", "cast 'fireball' orc"},
+	{"give $2 $1", "sword Bob", "give Bob sword"},
+	{"tell Bob $*", "Hello There", "tell Bob Hello There"},
+	{"say $3", "a b", "say "},
+	{"say costs $$5", "", "say costs $5"},
+	{"say $x", "", "say $x"},
+	{"say $12", "1 2 3 4 5 6 7 8 9 10 11 twelve", "say twelve"},
+}
+
+func TestSubstituteAliasArguments(t *testing.T) {
+	for _, test := range aliasSubstitutionTests {
+		result := substituteAliasArguments(test.body, test.arguments)
+		if result != test.expected {
+			t.Errorf("substituteAliasArguments(%q, %q) = %q, expected %q", test.body, test.arguments, result, test.expected)
+		}
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"k":     "kill",
+		"look":  "look;scan",
+		"combo": "k $1;cast 'fireball' $1",
+		"ping":  "pong",
+		"pong":  "ping",
+	}
+
+	commands, err := expandAliases(aliases, "combo orc")
+	if err != nil {
+		t.Fatalf("expandAliases failed: %v", err)
+	}
+
+	expected := []string{"kill orc", "cast 'fireball' orc"}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expandAliases(combo orc) = %v, expected %v", commands, expected)
+	}
+
+	commands, err = expandAliases(aliases, "look")
+	if err != nil || !reflect.DeepEqual(commands, []string{"look", "scan"}) {
+		t.Errorf("expandAliases(look) = %v, %v; expected [look scan]", commands, err)
+	}
+
+	commands, err = expandAliases(aliases, "score")
+	if err != nil || !reflect.DeepEqual(commands, []string{"score"}) {
+		t.Errorf("expandAliases(score) = %v, %v; expected [score]", commands, err)
+	}
+
+	/* Mutually recursive aliases stop at the one already being expanded */
+	commands, err = expandAliases(aliases, "ping")
+	if err != nil || !reflect.DeepEqual(commands, []string{"ping"}) {
+		t.Errorf("expandAliases(ping) = %v, %v; expected [ping]", commands, err)
+	}
+}
+
+func TestExpandAliasesLimits(t *testing.T) {
+	deep := map[string]string{"a1": "a2", "a2": "a3", "a3": "a4", "a4": "a5", "a5": "a6", "a6": "look"}
+
+	_, err := expandAliases(deep, "a1")
+	if err != ErrAliasTooDeep {
+		t.Errorf("expected ErrAliasTooDeep, got %v", err)
+	}
+
+	wide := map[string]string{"x": "y;y;y;y;y", "y": "look;look;look;look;look"}
+
+	_, err = expandAliases(wide, "x")
+	if err != ErrAliasTooLong {
+		t.Errorf("expected ErrAliasTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+/* How often players and planes with unsaved changes are written back */
+const AutosaveInterval = 5 * time.Minute
+
+/* Players persisted per transaction; keeps any single transaction short */
+const AutosaveBatchSize = 16
+
+/* Write a batch of players inside one transaction, returning how many were saved */
+func (game *Game) savePlayerBatch(batch []*Character) int {
+	tx, err := game.db.Begin()
+	if err != nil {
+		log.Printf("Autosave: failed to begin transaction: %v\r\n", err)
+		return 0
+	}
+
+	for _, ch := range batch {
+		err = ch.persist(tx)
+		if err != nil {
+			log.Printf("Autosave: failed to save player %s, rolling back batch: %v\r\n", ch.Name, err)
+			tx.Rollback()
+			return 0
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Autosave: failed to commit batch: %v\r\n", err)
+		return 0
+	}
+
+	for _, ch := range batch {
+		ch.dirty = false
+	}
+
+	return len(batch)
+}
+
+/* Save every plane whose terrain changed, returning how many were saved */
+func (game *Game) savePlanes() int {
+	var saved int = 0
+
+	for iter := game.Planes.Head; iter != nil; iter = iter.Next {
+		plane := iter.Value.(*Plane)
+
+		if !plane.Dirty {
+			continue
+		}
+
+		err := plane.SaveBlob()
+		if err != nil {
+			log.Printf("Failed to save plane %d: %v\r\n", plane.Id, err)
+			continue
+		}
+
+		saved++
+	}
+
+	return saved
+}
+
+/* Persist only what changed since the last save and report the cost to wiznet */
+func (game *Game) autosave() {
+	startedAt := time.Now()
+
+	var players int = 0
+	var batch []*Character = make([]*Character, 0, AutosaveBatchSize)
+
+	for iter := game.Characters.Head; iter != nil; iter = iter.Next {
+		ch := iter.Value.(*Character)
+
+		if ch.Flags&CHAR_IS_PLAYER == 0 || !ch.dirty {
+			continue
+		}
+
+		batch = append(batch, ch)
+		if len(batch) == AutosaveBatchSize {
+			players += game.savePlayerBatch(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		players += game.savePlayerBatch(batch)
+	}
+
+	planes := game.savePlanes()
+
+	if players == 0 && planes == 0 {
+		return
+	}
+
+	out := fmt.Sprintf("Autosave: saved %d players and %d planes in %v.\r\n", players, planes, time.Since(startedAt).Round(time.Millisecond))
+	log.Print(out)
+	game.broadcast(out, WiznetBroadcastFilter)
+}
@@ -0,0 +1,427 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BanTypeHost     = "host"
+	BanTypeCIDR     = "cidr"
+	BanTypeUsername = "username"
+)
+
+/* Failed logins tolerated before the delay starts doubling */
+const LoginFailuresBeforeDelay = 2
+
+/* Delay after the first counted failure, doubled for each one after */
+const LoginFailureBaseDelay = 1 * time.Second
+const LoginFailureMaxDelay = 32 * time.Second
+
+/* Failures after which an address, or an account from that address, is locked out entirely */
+const LoginLockoutThreshold = 10
+const LoginLockoutDuration = 15 * time.Minute
+
+/* A counter with no new failures for this long starts over */
+const LoginFailureWindow = 15 * time.Minute
+
+type Ban struct {
+	Id        int       `json:"id"`
+	Type      string    `json:"type"`
+	Pattern   string    `json:"pattern"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	network *net.IPNet
+}
+
+type loginFailure struct {
+	count int
+	last  time.Time
+}
+
+/*
+ * Failed password attempts, counted by remote address and by account from that address.
+ * Nothing is counted against an account name alone, so knowing a player's name isn't
+ * enough to slow down or lock out their own logins from elsewhere; the escalating delay
+ * follows the address.  Only touched from the game loop.
+ */
+type LoginThrottle struct {
+	failures map[string]*loginFailure
+}
+
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{failures: make(map[string]*loginFailure)}
+}
+
+func loginThrottleHostKey(host string) string {
+	return "host:" + host
+}
+
+func loginThrottleKeys(host string, username string) []string {
+	return []string{loginThrottleHostKey(host), loginThrottleHostKey(host) + " username:" + strings.ToLower(username)}
+}
+
+func (throttle *LoginThrottle) current(key string, now time.Time) *loginFailure {
+	failure, ok := throttle.failures[key]
+	if !ok {
+		return nil
+	}
+
+	if now.Sub(failure.last) > LoginFailureWindow {
+		delete(throttle.failures, key)
+		return nil
+	}
+
+	return failure
+}
+
+/* Forget every counter which has gone quiet, so a spray of hosts and names doesn't pile up */
+func (throttle *LoginThrottle) Sweep(now time.Time) {
+	for key, failure := range throttle.failures {
+		if now.Sub(failure.last) > LoginFailureWindow {
+			delete(throttle.failures, key)
+		}
+	}
+}
+
+/* Record a failed attempt, returning how long the client should wait before its next */
+func (throttle *LoginThrottle) RecordFailure(host string, username string, now time.Time) time.Duration {
+	for _, key := range loginThrottleKeys(host, username) {
+		failure := throttle.current(key, now)
+		if failure == nil {
+			failure = &loginFailure{}
+			throttle.failures[key] = failure
+		}
+
+		failure.count++
+		failure.last = now
+	}
+
+	count := throttle.failures[loginThrottleHostKey(host)].count
+	if count <= LoginFailuresBeforeDelay {
+		return 0
+	}
+
+	delay := LoginFailureBaseDelay << uint(count-LoginFailuresBeforeDelay-1)
+	if delay > LoginFailureMaxDelay || delay <= 0 {
+		delay = LoginFailureMaxDelay
+	}
+
+	return delay
+}
+
+func (throttle *LoginThrottle) RecordSuccess(host string, username string) {
+	for _, key := range loginThrottleKeys(host, username) {
+		delete(throttle.failures, key)
+	}
+}
+
+func (throttle *LoginThrottle) IsLockedOut(host string, username string, now time.Time) bool {
+	for _, key := range loginThrottleKeys(host, username) {
+		failure := throttle.current(key, now)
+
+		if failure != nil && failure.count >= LoginLockoutThreshold && now.Sub(failure.last) < LoginLockoutDuration {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* The bare IP address of a remote peer, without its port */
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
+
+func (ban *Ban) compile() error {
+	switch ban.Type {
+	case BanTypeHost:
+		if net.ParseIP(ban.Pattern) == nil {
+			return fmt.Errorf("%s is not an IP address", ban.Pattern)
+		}
+
+	case BanTypeCIDR:
+		_, network, err := net.ParseCIDR(ban.Pattern)
+		if err != nil {
+			return err
+		}
+
+		ban.network = network
+
+	case BanTypeUsername:
+		if ban.Pattern == "" {
+			return errors.New("empty username")
+		}
+
+	default:
+		return fmt.Errorf("unknown ban type %s", ban.Type)
+	}
+
+	return nil
+}
+
+func (ban *Ban) MatchesHost(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	switch ban.Type {
+	case BanTypeHost:
+		return ip.Equal(net.ParseIP(ban.Pattern))
+
+	case BanTypeCIDR:
+		return ban.network != nil && ban.network.Contains(ip)
+	}
+
+	return false
+}
+
+func (ban *Ban) MatchesUsername(username string) bool {
+	return ban.Type == BanTypeUsername && strings.EqualFold(ban.Pattern, username)
+}
+
+func (game *Game) LoadBans() error {
+	log.Printf("Loading bans.\r\n")
+
+	game.bans = NewLinkedList()
+
+	rows, err := game.db.Query(`
+		SELECT
+			id,
+			ban_type,
+			pattern,
+			reason,
+			created_by,
+			created_at
+		FROM
+			bans
+		WHERE
+			deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var reason sql.NullString
+
+		ban := &Ban{}
+
+		err := rows.Scan(&ban.Id, &ban.Type, &ban.Pattern, &reason, &ban.CreatedBy, &ban.CreatedAt)
+		if err != nil {
+			log.Printf("Unable to scan ban: %v.\r\n", err)
+			return err
+		}
+
+		ban.Reason = reason.String
+
+		err = ban.compile()
+		if err != nil {
+			log.Printf("Ignoring malformed ban %d: %v.\r\n", ban.Id, err)
+			continue
+		}
+
+		game.bans.Insert(ban)
+	}
+
+	return nil
+}
+
+func (game *Game) CreateBan(banType string, pattern string, reason string, createdBy string) (*Ban, error) {
+	ban := &Ban{Type: banType, Pattern: pattern, Reason: reason, CreatedBy: createdBy, CreatedAt: time.Now()}
+
+	err := ban.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := game.db.Exec(`
+		INSERT INTO
+			bans(ban_type, pattern, reason, created_by)
+		VALUES
+			(?, ?, ?, ?)
+	`, ban.Type, ban.Pattern, ban.Reason, ban.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	ban.Id = int(id)
+	game.bans.Insert(ban)
+	return ban, nil
+}
+
+func (game *Game) RemoveBan(ban *Ban) error {
+	_, err := game.db.Exec(`
+		UPDATE
+			bans
+		SET
+			deleted_at = NOW()
+		WHERE
+			id = ?
+	`, ban.Id)
+	if err != nil {
+		return err
+	}
+
+	game.bans.Remove(ban)
+	return nil
+}
+
+func (game *Game) findBan(match func(ban *Ban) bool) *Ban {
+	for iter := game.bans.Head; iter != nil; iter = iter.Next {
+		ban := iter.Value.(*Ban)
+
+		if match(ban) {
+			return ban
+		}
+	}
+
+	return nil
+}
+
+func (game *Game) FindHostBan(host string) *Ban {
+	return game.findBan(func(ban *Ban) bool {
+		return ban.MatchesHost(host)
+	})
+}
+
+func (game *Game) FindUsernameBan(username string) *Ban {
+	return game.findBan(func(ban *Ban) bool {
+		return ban.MatchesUsername(username)
+	})
+}
+
+/* Tell wiznet a ban turned someone away */
+func (game *Game) reportBanHit(ban *Ban, client *Client, subject string) {
+	out := fmt.Sprintf("Ban #%d (%s %s) refused %s from %s.\r\n", ban.Id, ban.Type, ban.Pattern, subject, remoteHost(client.conn.RemoteAddr()))
+
+	log.Print(out)
+	game.broadcast(out, WiznetBroadcastFilter)
+}
+
+/* Send a parting message and hang up without holding up the game loop */
+func (client *Client) refuse(message string) {
+	client.ConnectionState = ConnectionStateNone
+	client.Send([]byte(message))
+
+	go func() {
+		client.stopWriting(ShutdownDrainTimeout)
+		client.conn.Close()
+	}()
+}
+
+func do_ban(ch *Character, arguments string) {
+	if len(arguments) < 1 {
+		output := "{WBan management:\r\n" +
+			"{Glist                           - {glist active bans\r\n" +
+			"{Ghost <address> [reason]        - {gban a single IP address{x\r\n" +
+			"{Gcidr <prefix> [reason]         - {gban a range, e.g. 192.0.2.0/24{x\r\n" +
+			"{Gusername <name> [reason]       - {gban an account or character name{x\r\n" +
+			"{Gremove <ban_id>                - {glift a ban by ID{x\r\n"
+		ch.Send(output)
+		return
+	}
+
+	firstArgument, arguments := OneArgument(arguments)
+
+	command := strings.ToLower(firstArgument)
+	switch command {
+	case "list":
+		var output strings.Builder
+
+		output.WriteString("{Y  ID# | Type     | Pattern                    | Set by       | Reason\r\n")
+		output.WriteString("------+----------+----------------------------+--------------+----------------------\r\n")
+
+		for iter := ch.Game.bans.Head; iter != nil; iter = iter.Next {
+			ban := iter.Value.(*Ban)
+
+			output.WriteString(fmt.Sprintf("{Y%5d | %-8s | %-26s | %-12s | %s\r\n", ban.Id, ban.Type, ban.Pattern, ban.CreatedBy, ban.Reason))
+		}
+
+		output.WriteString("{x")
+		ch.Send(output.String())
+
+	case BanTypeHost, BanTypeCIDR, BanTypeUsername:
+		pattern, reason := OneArgument(arguments)
+		if pattern == "" {
+			ch.Send(fmt.Sprintf("Usage: ban %s <pattern> [reason]\r\n", command))
+			break
+		}
+
+		if command == BanTypeUsername {
+			pattern = strings.Title(strings.ToLower(pattern))
+		}
+
+		ban, err := ch.Game.CreateBan(command, pattern, strings.TrimSpace(reason), ch.Name)
+		if err != nil {
+			ch.Send(fmt.Sprintf("Something went wrong trying to create that ban: %v\r\n", err))
+			break
+		}
+
+		out := fmt.Sprintf("%s added ban #%d on %s %s.\r\n", ch.Name, ban.Id, ban.Type, ban.Pattern)
+		log.Print(out)
+		ch.Game.broadcast(out, WiznetBroadcastFilter)
+
+		ch.Send("Ok.\r\n")
+
+	case "remove":
+		secondArgument, _ := OneArgument(arguments)
+
+		banId, err := strconv.Atoi(secondArgument)
+		if err != nil {
+			ch.Send("Bad argument, please provide an integer ban ID.\r\n")
+			break
+		}
+
+		ban := ch.Game.findBan(func(ban *Ban) bool {
+			return ban.Id == banId
+		})
+		if ban == nil {
+			ch.Send("No ban exists with that ID.\r\n")
+			break
+		}
+
+		err = ch.Game.RemoveBan(ban)
+		if err != nil {
+			ch.Send(fmt.Sprintf("Something went wrong trying to remove that ban: %v\r\n", err))
+			break
+		}
+
+		out := fmt.Sprintf("%s lifted ban #%d on %s %s.\r\n", ch.Name, ban.Id, ban.Type, ban.Pattern)
+		log.Print(out)
+		ch.Game.broadcast(out, WiznetBroadcastFilter)
+
+		ch.Send("Ok.\r\n")
+
+	default:
+		ch.Send("Unknown ban command.\r\n")
+	}
+}
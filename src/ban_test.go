@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+type banMatchTest struct {
+	banType, pattern, host string
+	expected               bool
+}
+
+var banMatchTests = []banMatchTest{
+	{BanTypeHost, "192.0.2.7", "192.0.2.7", true},
+	{BanTypeHost, "192.0.2.7", "192.0.2.8", false},
+	{BanTypeHost, "2001:db8::1", "2001:db8:0::1", true},
+	{BanTypeCIDR, "192.0.2.0/24", "192.0.2.200", true},
+	{BanTypeCIDR, "192.0.2.0/24", "192.0.3.1", false},
+	{BanTypeCIDR, "2001:db8::/32", "2001:db8:1234::5", true},
+	{BanTypeCIDR, "192.0.2.0/24", "not-an-address", false},
+	{BanTypeUsername, "Bob", "192.0.2.7", false},
+}
+
+func TestBanMatchesHost(t *testing.T) {
+	for _, test := range banMatchTests {
+		ban := &Ban{Type: test.banType, Pattern: test.pattern}
+
+		err := ban.compile()
+		if err != nil {
+			t.Fatalf("compile(%s %s) failed: %v", test.banType, test.pattern, err)
+		}
+
+		if ban.MatchesHost(test.host) != test.expected {
+			t.Errorf("%s ban %s matching %s: expected %v", test.banType, test.pattern, test.host, test.expected)
+		}
+	}
+}
+
+func TestBanMatchesUsername(t *testing.T) {
+	ban := &Ban{Type: BanTypeUsername, Pattern: "Bob"}
+
+	if !ban.MatchesUsername("bob") {
+		t.Errorf("username ban should match case-insensitively")
+	}
+
+	if ban.MatchesUsername("Bobby") {
+		t.Errorf("username ban should only match the exact name")
+	}
+}
+
+func TestBanRejectsMalformedPatterns(t *testing.T) {
+	for _, ban := range []*Ban{
+		{Type: BanTypeHost, Pattern: "192.0.2.0/24"},
+		{Type: BanTypeCIDR, Pattern: "192.0.2.7"},
+		{Type: "planet", Pattern: "Earth"},
+	} {
+		if ban.compile() == nil {
+			t.Errorf("expected %s ban %q to be rejected", ban.Type, ban.Pattern)
+		}
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.7"), Port: 4000}
+
+	if remoteHost(addr) != "192.0.2.7" {
+		t.Errorf("remoteHost(%s) = %s", addr, remoteHost(addr))
+	}
+}
+
+func TestLoginThrottleDelayDoubles(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		delays = append(delays, throttle.RecordFailure("192.0.2.7", "Bob", now))
+	}
+
+	expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("failure %d: expected delay %v, got %v", i+1, expected[i], delays[i])
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		throttle.RecordFailure("192.0.2.7", "Bob", now)
+	}
+
+	if delay := throttle.RecordFailure("192.0.2.7", "Bob", now); delay != LoginFailureMaxDelay {
+		t.Errorf("expected delay to be capped at %v, got %v", LoginFailureMaxDelay, delay)
+	}
+}
+
+func TestLoginThrottleDelayFollowsHost(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	/* Failing against somebody else's account from many hosts never slows any one of them */
+	for i := 0; i < LoginLockoutThreshold-1; i++ {
+		if delay := throttle.RecordFailure(net.IPv4(192, 0, 2, byte(i)).String(), "Bob", now); delay != 0 {
+			t.Errorf("failure %d: expected no delay from a fresh host, got %v", i+1, delay)
+		}
+	}
+
+	/* Nor does a host's history follow it onto the failures of other hosts */
+	for i := 0; i < 3; i++ {
+		throttle.RecordFailure("198.51.100.1", "Alice", now)
+	}
+
+	if delay := throttle.RecordFailure("198.51.100.2", "Alice", now); delay != 0 {
+		t.Errorf("expected no delay for a different host, got %v", delay)
+	}
+}
+
+func TestLoginThrottleLockout(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	/* Spread across hosts, failures against one username lock nobody out */
+	for i := 0; i < LoginLockoutThreshold; i++ {
+		throttle.RecordFailure(net.IPv4(192, 0, 2, byte(i)).String(), "Bob", now)
+	}
+
+	if throttle.IsLockedOut("198.51.100.1", "Bob", now) {
+		t.Errorf("expected failures from other hosts not to lock the account out")
+	}
+
+	for i := 0; i < LoginLockoutThreshold; i++ {
+		throttle.RecordFailure("203.0.113.5", "Bob", now)
+	}
+
+	if !throttle.IsLockedOut("203.0.113.5", "bob", now) {
+		t.Errorf("expected the failing host to be locked out")
+	}
+
+	if throttle.IsLockedOut("198.51.100.1", "Bob", now) {
+		t.Errorf("expected the account to stay usable from a clean host")
+	}
+
+	if throttle.IsLockedOut("203.0.113.5", "Bob", now.Add(LoginLockoutDuration+time.Second)) {
+		t.Errorf("expected lockout to expire")
+	}
+
+	throttle.RecordSuccess("203.0.113.5", "Bob")
+	if throttle.IsLockedOut("203.0.113.5", "Bob", now) {
+		t.Errorf("expected a successful login to clear the counter")
+	}
+}
+
+func TestLoginThrottleSweep(t *testing.T) {
+	throttle := NewLoginThrottle()
+	now := time.Now()
+
+	for i := 0; i < 50; i++ {
+		throttle.RecordFailure(net.IPv4(192, 0, 2, byte(i)).String(), "Bob", now)
+	}
+
+	throttle.RecordFailure("198.51.100.1", "Alice", now.Add(LoginFailureWindow))
+	throttle.Sweep(now.Add(LoginFailureWindow + time.Second))
+
+	if len(throttle.failures) != 2 {
+		t.Errorf("expected only the recent host and account counters to remain, got %d", len(throttle.failures))
+	}
+}
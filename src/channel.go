@@ -0,0 +1,372 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+/* The immortal channel, built in because game notices are broadcast on it */
+const WiznetChannelName = "wiznet"
+
+const MaxChannelNameLength = 32
+const DefaultChannelHistoryLength = 20
+
+/*
+ * A channel is a global talker defined by data rather than code: rows in the channels
+ * table, plus any registered by scripts through Golem.registerChannel.  Each channel
+ * becomes a command of the same name unless that name is already taken.
+ */
+type Channel struct {
+	Id            int    `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Colour        string `json:"colour"`
+	MinimumLevel  uint   `json:"minimumLevel"`
+	HistoryLength int    `json:"historyLength"`
+	DefaultOn     bool   `json:"defaultOn"`
+	Scripted      bool   `json:"scripted"`
+
+	history *ChannelHistory
+}
+
+func (game *Game) LoadChannels() error {
+	log.Printf("Loading channels.\r\n")
+
+	game.channels = make(map[string]*Channel)
+	game.registerChannel(&Channel{
+		Name:          WiznetChannelName,
+		Description:   "Game notices and immortal chatter",
+		Colour:        "{R",
+		MinimumLevel:  LevelAdmin,
+		HistoryLength: DefaultChannelHistoryLength,
+		DefaultOn:     false,
+	})
+
+	rows, err := game.db.Query(`
+		SELECT
+			id,
+			name,
+			COALESCE(description, ''),
+			colour,
+			minimum_level,
+			history_length,
+			default_on
+		FROM
+			channels
+		WHERE
+			deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		channel := &Channel{}
+
+		err := rows.Scan(&channel.Id, &channel.Name, &channel.Description, &channel.Colour, &channel.MinimumLevel, &channel.HistoryLength, &channel.DefaultOn)
+		if err != nil {
+			log.Printf("Unable to scan channel: %v.\r\n", err)
+			return err
+		}
+
+		game.registerChannel(channel)
+	}
+
+	return nil
+}
+
+/* Add or replace a channel definition, giving it a command if the name is free */
+func (game *Game) registerChannel(channel *Channel) {
+	channel.Name = strings.ToLower(channel.Name)
+
+	game.initChannelHistory(channel, game.channels[channel.Name])
+	game.channels[channel.Name] = channel
+
+	command, exists := CommandTable[channel.Name]
+	if exists && !command.Channel {
+		return
+	}
+
+	name := channel.Name
+	CommandTable[name] = Command{
+		Name:         name,
+		MinimumLevel: channel.MinimumLevel,
+		CmdFunc: func(ch *Character, arguments string) {
+			ch.useChannel(name, arguments)
+		},
+		Channel:         true,
+		MinimumPosition: PositionSleeping,
+	}
+
+	rebuildCommandOrder()
+}
+
+func (game *Game) findChannel(name string) *Channel {
+	return game.channels[strings.ToLower(name)]
+}
+
+/* Channels in name order, for listings */
+func (game *Game) sortedChannels() []*Channel {
+	channels := make([]*Channel, 0, len(game.channels))
+
+	for _, channel := range game.channels {
+		channels = append(channels, channel)
+	}
+
+	sort.Slice(channels, func(i int, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+
+	return channels
+}
+
+func (ch *Character) canUseChannel(channel *Channel) bool {
+	return channel != nil && ch.Level >= channel.MinimumLevel
+}
+
+/* Whether this character hears a channel, by their own choice or the channel's default */
+func (ch *Character) isListening(channel *Channel) bool {
+	if !ch.canUseChannel(channel) {
+		return false
+	}
+
+	listening, ok := ch.channels[channel.Name]
+	if !ok {
+		return channel.DefaultOn
+	}
+
+	return listening
+}
+
+func (ch *Character) LoadPlayerChannels() error {
+	rows, err := ch.Game.db.Query(`
+		SELECT
+			channel,
+			listening
+		FROM
+			pc_channel
+		WHERE
+			player_character_id = ?
+	`, ch.Id)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var listening bool
+
+		err := rows.Scan(&name, &listening)
+		if err != nil {
+			return err
+		}
+
+		ch.channels[name] = listening
+	}
+
+	return nil
+}
+
+/* Join or leave a channel, remembering the choice across sessions */
+func (ch *Character) setListening(channel *Channel, listening bool) error {
+	if ch.Flags&CHAR_IS_PLAYER != 0 && ch.Id > 0 {
+		_, err := ch.Game.db.Exec(`
+			INSERT INTO
+				pc_channel(player_character_id, channel, listening)
+			VALUES
+				(?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				listening = VALUES(listening)
+		`, ch.Id, channel.Name, listening)
+		if err != nil {
+			return err
+		}
+	}
+
+	ch.channels[channel.Name] = listening
+	return nil
+}
+
+/*
+ * Speak on a channel to everyone in the game listening to it.  Handlers of the
+ * onChannelMessage event see each message first, and any of them returning false
+ * keeps it from being sent.
+ */
+func (game *Game) channelMessage(channel *Channel, talker *Character, message string) bool {
+	values, _ := game.InvokeNamedEventHandlersWithContextAndArguments("onChannelMessage", game.vm.ToValue(game), game.vm.ToValue(talker), game.vm.ToValue(channel), game.vm.ToValue(message))
+	for _, value := range values {
+		if value != nil && value.StrictEquals(game.vm.ToValue(false)) {
+			return false
+		}
+	}
+
+	output := fmt.Sprintf("\r\n%s[%s] %s: %s{x\r\n", channel.Colour, strings.ToUpper(channel.Name), talker.Name, message)
+	notIgnoring := IgnoreBroadcastFilter(talker)
+
+	for client := range game.clients {
+		if client.Character == nil || client.ConnectionState != ConnectionStatePlaying {
+			continue
+		}
+
+		if !client.Character.isListening(channel) || !notIgnoring(client.Character) {
+			continue
+		}
+
+		client.Character.Send(output)
+		client.Character.sendGMCPChannel(channel.Name, talker, output)
+	}
+
+	game.recordChannelMessage(channel, ChannelMessage{Talker: talker.Name, Message: message, SentAt: time.Now()})
+	return true
+}
+
+/* The command behind every channel: talk on it, or toggle it with no message */
+func (ch *Character) useChannel(name string, arguments string) {
+	channel := ch.Game.findChannel(name)
+	if !ch.canUseChannel(channel) {
+		ch.Send(fmt.Sprintf("{RAlas, there is no such command: %s{x\r\n", name))
+		return
+	}
+
+	message := strings.TrimSpace(arguments)
+	if message == "" {
+		listening := !ch.isListening(channel)
+
+		err := ch.setListening(channel, listening)
+		if err != nil {
+			log.Printf("Failed to update channel %s for %s: %v.\r\n", channel.Name, ch.Name, err)
+			ch.Send("A strange force prevents you from changing your channels.\r\n")
+			return
+		}
+
+		if listening {
+			ch.Send(fmt.Sprintf("%s%s channel is now ON.{x\r\n", channel.Colour, strings.Title(channel.Name)))
+		} else {
+			ch.Send(fmt.Sprintf("%s%s channel is now OFF.{x\r\n", channel.Colour, strings.Title(channel.Name)))
+		}
+
+		return
+	}
+
+	if !ch.isListening(channel) {
+		ch.Send(fmt.Sprintf("You aren't listening to %s; type '%s' to turn it on.\r\n", channel.Name, channel.Name))
+		return
+	}
+
+	if !ch.Game.channelMessage(channel, ch, message) {
+		ch.Send("Your message was not sent.\r\n")
+	}
+}
+
+/* Build a channel from the object passed to Golem.registerChannel */
+func (game *Game) channelFromScript(definition *goja.Object) (*Channel, error) {
+	channel := &Channel{
+		Colour:        "{x",
+		HistoryLength: DefaultChannelHistoryLength,
+		DefaultOn:     true,
+		Scripted:      true,
+	}
+
+	name := definition.Get("name")
+	if name == nil || goja.IsUndefined(name) || goja.IsNull(name) {
+		return nil, fmt.Errorf("channel definition has no name")
+	}
+
+	channel.Name = strings.ToLower(strings.TrimSpace(name.String()))
+	if channel.Name == "" || len(channel.Name) > MaxChannelNameLength || strings.ContainsAny(channel.Name, " \t") {
+		return nil, fmt.Errorf("invalid channel name %q", channel.Name)
+	}
+
+	if value := definition.Get("description"); value != nil && !goja.IsUndefined(value) {
+		channel.Description = value.String()
+	}
+
+	if value := definition.Get("colour"); value != nil && !goja.IsUndefined(value) {
+		channel.Colour = value.String()
+	}
+
+	if value := definition.Get("minimumLevel"); value != nil && !goja.IsUndefined(value) {
+		channel.MinimumLevel = uint(value.ToInteger())
+	}
+
+	if value := definition.Get("historyLength"); value != nil && !goja.IsUndefined(value) {
+		channel.HistoryLength = int(value.ToInteger())
+	}
+
+	if value := definition.Get("defaultOn"); value != nil && !goja.IsUndefined(value) {
+		channel.DefaultOn = value.ToBoolean()
+	}
+
+	return channel, nil
+}
+
+func do_channels(ch *Character, arguments string) {
+	firstArgument, arguments := OneArgument(arguments)
+
+	switch strings.ToLower(firstArgument) {
+	case "":
+		var output strings.Builder
+
+		output.WriteString("{Y Channel      | Status | Description\r\n")
+		output.WriteString("--------------+--------+------------------------------------------\r\n")
+
+		for _, channel := range ch.Game.sortedChannels() {
+			if !ch.canUseChannel(channel) {
+				continue
+			}
+
+			status := "{ROFF{Y"
+			if ch.isListening(channel) {
+				status = "{G ON{Y"
+			}
+
+			output.WriteString(fmt.Sprintf("{Y %-12s |  %s   | %s\r\n", channel.Name, status, channel.Description))
+		}
+
+		output.WriteString("{x\r\nType 'channels join <channel>' or 'channels leave <channel>' to change them.\r\n")
+		ch.Send(output.String())
+
+	case "join", "leave":
+		secondArgument, _ := OneArgument(arguments)
+
+		channel := ch.Game.findChannel(secondArgument)
+		if !ch.canUseChannel(channel) {
+			ch.Send("No such channel.\r\n")
+			return
+		}
+
+		listening := strings.ToLower(firstArgument) == "join"
+
+		err := ch.setListening(channel, listening)
+		if err != nil {
+			log.Printf("Failed to update channel %s for %s: %v.\r\n", channel.Name, ch.Name, err)
+			ch.Send("A strange force prevents you from changing your channels.\r\n")
+			return
+		}
+
+		if listening {
+			ch.Send(fmt.Sprintf("You join the %s channel.\r\n", channel.Name))
+		} else {
+			ch.Send(fmt.Sprintf("You leave the %s channel.\r\n", channel.Name))
+		}
+
+	default:
+		ch.Send("Usage: channels [join|leave <channel>]\r\n")
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type ChannelMessage struct {
+	Talker  string    `json:"talker"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+/* The most recent messages on a channel, oldest overwritten first */
+type ChannelHistory struct {
+	messages []ChannelMessage
+	start    int
+	count    int
+}
+
+func NewChannelHistory(capacity int) *ChannelHistory {
+	if capacity < 0 {
+		capacity = 0
+	}
+
+	return &ChannelHistory{messages: make([]ChannelMessage, capacity)}
+}
+
+func (history *ChannelHistory) Add(message ChannelMessage) {
+	capacity := len(history.messages)
+	if capacity == 0 {
+		return
+	}
+
+	if history.count < capacity {
+		history.messages[(history.start+history.count)%capacity] = message
+		history.count++
+		return
+	}
+
+	history.messages[history.start] = message
+	history.start = (history.start + 1) % capacity
+}
+
+/* Every message held, oldest first */
+func (history *ChannelHistory) Messages() []ChannelMessage {
+	messages := make([]ChannelMessage, 0, history.count)
+
+	for index := 0; index < history.count; index++ {
+		messages = append(messages, history.messages[(history.start+index)%len(history.messages)])
+	}
+
+	return messages
+}
+
+/* Messages sent after a moment, oldest first */
+func (history *ChannelHistory) Since(since time.Time) []ChannelMessage {
+	messages := make([]ChannelMessage, 0)
+
+	for _, message := range history.Messages() {
+		if message.SentAt.After(since) {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+func channelHistoryKey(name string) string {
+	return fmt.Sprintf("golem:channel-history:%s", name)
+}
+
+/* Give a channel its history, carried over from a definition it replaces or read back from Redis */
+func (game *Game) initChannelHistory(channel *Channel, previous *Channel) {
+	channel.history = NewChannelHistory(channel.HistoryLength)
+
+	if previous != nil && previous.history != nil {
+		for _, message := range previous.history.Messages() {
+			channel.history.Add(message)
+		}
+
+		return
+	}
+
+	if !Config.RedisConfiguration.ChannelHistory || channel.HistoryLength <= 0 {
+		return
+	}
+
+	conn := game.redis.Get()
+	defer conn.Close()
+
+	entries, err := redis.ByteSlices(conn.Do("LRANGE", channelHistoryKey(channel.Name), -channel.HistoryLength, -1))
+	if err != nil {
+		log.Printf("Unable to read history for channel %s from Redis: %v.\r\n", channel.Name, err)
+		return
+	}
+
+	for _, entry := range entries {
+		var message ChannelMessage
+
+		err := json.Unmarshal(entry, &message)
+		if err != nil {
+			continue
+		}
+
+		channel.history.Add(message)
+	}
+}
+
+func (game *Game) recordChannelMessage(channel *Channel, message ChannelMessage) {
+	if channel.HistoryLength <= 0 || channel.history == nil {
+		return
+	}
+
+	channel.history.Add(message)
+
+	if !Config.RedisConfiguration.ChannelHistory {
+		return
+	}
+
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	conn := game.redis.Get()
+	defer conn.Close()
+
+	key := channelHistoryKey(channel.Name)
+
+	conn.Send("MULTI")
+	conn.Send("RPUSH", key, encoded)
+	conn.Send("LTRIM", key, -channel.HistoryLength, -1)
+
+	_, err = conn.Do("EXEC")
+	if err != nil {
+		log.Printf("Unable to store history for channel %s in Redis: %v.\r\n", channel.Name, err)
+	}
+}
+
+func (ch *Character) formatChannelHistory(channel *Channel, messages []ChannelMessage, buf *strings.Builder) int {
+	var shown int = 0
+
+	for _, message := range messages {
+		if ch.isIgnoringName(message.Talker) {
+			continue
+		}
+
+		buf.WriteString(fmt.Sprintf("%s[%s] [%s] %s: %s{x\r\n", channel.Colour, message.SentAt.Format("15:04"), strings.ToUpper(channel.Name), message.Talker, message.Message))
+		shown++
+	}
+
+	return shown
+}
+
+/* Summarize what was said on a reconnecting player's channels since their link dropped */
+func (ch *Character) showMissedChannelMessages(since time.Time) {
+	var buf strings.Builder
+	var shown int = 0
+
+	for _, channel := range ch.Game.sortedChannels() {
+		if !ch.isListening(channel) || channel.history == nil {
+			continue
+		}
+
+		shown += ch.formatChannelHistory(channel, channel.history.Since(since), &buf)
+	}
+
+	if shown == 0 {
+		return
+	}
+
+	ch.Send(fmt.Sprintf("\r\n{WWhile you were away:{x\r\n%s", buf.String()))
+}
+
+func do_history(ch *Character, arguments string) {
+	name, _ := OneArgument(arguments)
+	if name == "" {
+		ch.Send("Usage: history <channel>\r\n")
+		return
+	}
+
+	channel := ch.Game.findChannel(name)
+	if !ch.canUseChannel(channel) {
+		ch.Send("No such channel.\r\n")
+		return
+	}
+
+	var buf strings.Builder
+
+	if channel.history == nil || ch.formatChannelHistory(channel, channel.history.Messages(), &buf) == 0 {
+		ch.Send(fmt.Sprintf("Nothing has been said on %s lately.\r\n", channel.Name))
+		return
+	}
+
+	ch.Send(buf.String())
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestChannelHistoryWrapsOldestFirst(t *testing.T) {
+	history := NewChannelHistory(3)
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for index := 0; index < 5; index++ {
+		history.Add(ChannelMessage{Talker: "Alice", Message: fmt.Sprintf("%d", index), SentAt: start.Add(time.Duration(index) * time.Minute)})
+	}
+
+	messages := history.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+
+	for index, expected := range []string{"2", "3", "4"} {
+		if messages[index].Message != expected {
+			t.Errorf("message %d = %q, expected %q", index, messages[index].Message, expected)
+		}
+	}
+
+	since := history.Since(start.Add(3 * time.Minute))
+	if len(since) != 1 || since[0].Message != "4" {
+		t.Errorf("Since returned %v, expected only message 4", since)
+	}
+}
+
+func TestChannelHistoryWithoutCapacity(t *testing.T) {
+	history := NewChannelHistory(0)
+	history.Add(ChannelMessage{Talker: "Alice", Message: "lost", SentAt: time.Now()})
+
+	if len(history.Messages()) != 0 {
+		t.Errorf("a channel without history kept a message")
+	}
+}
@@ -10,10 +10,7 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"context"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"math"
@@ -23,7 +20,6 @@ import (
 	"unicode"
 
 	"github.com/dop251/goja"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const UnauthenticatedUsername = "unnamed"
@@ -44,6 +40,10 @@ type Race struct {
 	DisplayName      string `json:"display_name"`
 	Playable         bool   `json:"playable"`
 	PrimaryAttribute int    `json:"primaryAttribute"`
+
+	Resist  int `json:"resist"`
+	Immune  int `json:"immune"`
+	Suscept int `json:"suscept"`
 }
 
 const LevelAdmin = 60
@@ -85,28 +85,47 @@ const (
 	STAT_MAX          = 8
 )
 
+/* Resistance, immunity and susceptibility masks hold one bit for each damage type */
 const (
-	RESIST_BASH   = 1
-	RESIST_FIRE   = 1 << 1
-	RESIST_COLD   = 1 << 2
-	RESIST_SHOCK  = 1 << 3
-	RESIST_POISON = 1 << 4
+	RESIST_BASH     = 1 << DamageTypeBash
+	RESIST_SLASH    = 1 << DamageTypeSlash
+	RESIST_STAB     = 1 << DamageTypeStab
+	RESIST_EXOTIC   = 1 << DamageTypeExotic
+	RESIST_FIRE     = 1 << DamageTypeFire
+	RESIST_COLD     = 1 << DamageTypeCold
+	RESIST_SHOCK    = 1 << DamageTypeShock
+	RESIST_POISON   = 1 << DamageTypePoison
+	RESIST_ACID     = 1 << DamageTypeAcid
+	RESIST_HOLY     = 1 << DamageTypeHoly
+	RESIST_NEGATIVE = 1 << DamageTypeNegative
 )
 
 const (
-	IMMUNE_BASH   = 1
-	IMMUNE_FIRE   = 1 << 1
-	IMMUNE_COLD   = 1 << 2
-	IMMUNE_SHOCK  = 1 << 3
-	IMMUNE_POISON = 1 << 4
+	IMMUNE_BASH     = 1 << DamageTypeBash
+	IMMUNE_SLASH    = 1 << DamageTypeSlash
+	IMMUNE_STAB     = 1 << DamageTypeStab
+	IMMUNE_EXOTIC   = 1 << DamageTypeExotic
+	IMMUNE_FIRE     = 1 << DamageTypeFire
+	IMMUNE_COLD     = 1 << DamageTypeCold
+	IMMUNE_SHOCK    = 1 << DamageTypeShock
+	IMMUNE_POISON   = 1 << DamageTypePoison
+	IMMUNE_ACID     = 1 << DamageTypeAcid
+	IMMUNE_HOLY     = 1 << DamageTypeHoly
+	IMMUNE_NEGATIVE = 1 << DamageTypeNegative
 )
 
 const (
-	SUSCEPT_BASH   = 1
-	SUSCEPT_FIRE   = 1 << 1
-	SUSCEPT_COLD   = 1 << 2
-	SUSCEPT_SHOCK  = 1 << 3
-	SUSCEPT_POISON = 1 << 4
+	SUSCEPT_BASH     = 1 << DamageTypeBash
+	SUSCEPT_SLASH    = 1 << DamageTypeSlash
+	SUSCEPT_STAB     = 1 << DamageTypeStab
+	SUSCEPT_EXOTIC   = 1 << DamageTypeExotic
+	SUSCEPT_FIRE     = 1 << DamageTypeFire
+	SUSCEPT_COLD     = 1 << DamageTypeCold
+	SUSCEPT_SHOCK    = 1 << DamageTypeShock
+	SUSCEPT_POISON   = 1 << DamageTypePoison
+	SUSCEPT_ACID     = 1 << DamageTypeAcid
+	SUSCEPT_HOLY     = 1 << DamageTypeHoly
+	SUSCEPT_NEGATIVE = 1 << DamageTypeNegative
 )
 
 const (
@@ -167,7 +186,8 @@ type Character struct {
 	Leader    *Character  `json:"leader"`
 	Group     *LinkedList `json:"group"`
 
-	Id int `json:"id"`
+	Id        int `json:"id"`
+	AccountId int `json:"accountId"`
 
 	Name             string `json:"name"`
 	ShortDescription string `json:"shortDescription"`
@@ -175,7 +195,6 @@ type Character struct {
 	Description      string `json:"description"`
 
 	Wizard bool  `json:"wizard"`
-	Wiznet bool  `json:"wiznet"`
 	Job    *Job  `json:"job"`
 	Race   *Race `json:"race"`
 
@@ -198,12 +217,50 @@ type Character struct {
 	Stamina    int `json:"stamina"`
 	MaxStamina int `json:"maxStamina"`
 
-	Position int
+	Position int `json:"position"`
+
+	/* Damage types this character shrugs off, ignores or is hurt worse by, on top of their race's */
+	Resist  int `json:"resist"`
+	Immune  int `json:"immune"`
+	Suscept int `json:"suscept"`
+
+	/* The furniture the character is sitting, resting or sleeping on */
+	On *ObjectInstance `json:"on"`
+
+	/* Pulses of lag before the character can act again */
+	Wait int `json:"wait"`
 
 	Stats   []int `json:"stats"`
 	Defense int
 
-	temporaryHash string
+	/* Persistent state has changed since the last save */
+	dirty bool
+
+	/* When the player's connection dropped, if it has */
+	linkdeadAt time.Time
+
+	/* Who last sent a tell, and tells waiting while this player was away */
+	replyTo     string
+	queuedTells []QueuedTell
+
+	/* Channels joined or left against their defaults, by name */
+	channels map[string]bool
+
+	/* Names of the players this one is ignoring, by id */
+	ignoring map[int]string
+
+	/* Player-defined command aliases, by name */
+	aliases map[string]string
+
+	/* Input held until the character's wait state passes */
+	inputQueue []QueuedCommand
+}
+
+/* Flag a player for the next autosave */
+func (ch *Character) markDirty() {
+	if ch.Flags&CHAR_IS_PLAYER != 0 {
+		ch.dirty = true
+	}
 }
 
 func (ch *Character) IsEqual(och *Character) bool {
@@ -220,31 +277,6 @@ func (ch *Character) experienceRequiredForLevel(level int) int {
 	return required
 }
 
-func (game *Game) AttemptLogin(username string, password string) bool {
-	var hash string
-
-	row := game.db.QueryRow(`
-		SELECT
-			password_hash
-		FROM
-			player_characters
-		WHERE
-			username = ?
-		AND
-			deleted_at IS NULL
-	`, username)
-
-	err := row.Scan(&hash)
-	if err != nil {
-		return false
-	}
-
-	sha256Sum := sha256.Sum256([]byte(password + Config.HashSalt))
-	saltedHash := hex.EncodeToString(sha256Sum[:])
-
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(saltedHash)) == nil
-}
-
 func FindCharacterFlag(flag string) *Flag {
 	for _, f := range CharacterFlagTable {
 		if strings.EqualFold(f.Name, flag) {
@@ -258,20 +290,26 @@ func FindCharacterFlag(flag string) *Flag {
 func (ch *Character) onUpdate() {
 	/*
 	 * Regenerate some health and mana every tick if not in a room with ROOM_EVIL_AURA set.
-	 * Always regenerate some stamina.
+	 * Always regenerate some stamina.  Sitting, resting or sleeping, and doing so on
+	 * comfortable furniture, speeds all of it up.
 	 */
+	multiplier := ch.regenMultiplier()
+
 	if ch.Room == nil || ch.Room.Flags&ROOM_EVIL_AURA == 0 {
 		if ch.Health < ch.MaxHealth {
-			ch.Health = int(math.Min(float64(ch.MaxHealth), float64(ch.Health+3)))
+			ch.Health = int(math.Min(float64(ch.MaxHealth), float64(ch.Health)+3*multiplier))
+			ch.markDirty()
 		}
 
 		if ch.Mana < ch.MaxMana {
-			ch.Mana = int(math.Min(float64(ch.MaxMana), float64(ch.Mana+7)))
+			ch.Mana = int(math.Min(float64(ch.MaxMana), float64(ch.Mana)+7*multiplier))
+			ch.markDirty()
 		}
 	}
 
 	if ch.Stamina < ch.MaxStamina {
-		ch.Stamina = int(math.Min(float64(ch.MaxStamina), float64(ch.Stamina+40)))
+		ch.Stamina = int(math.Min(float64(ch.MaxStamina), float64(ch.Stamina)+40*multiplier))
+		ch.markDirty()
 	}
 }
 
@@ -283,11 +321,10 @@ func (ch *Character) Finalize() error {
 
 	result, err := ch.Game.db.Exec(`
 		INSERT INTO
-			player_characters(username, password_hash, wizard, room_id, race_id, job_id, level, gold, experience, practices, health, max_health, mana, max_mana, stamina, max_stamina, stat_str, stat_dex, stat_int, stat_wis, stat_con, stat_cha, stat_lck)
+			player_characters(account_id, username, wizard, room_id, race_id, job_id, level, gold, experience, practices, health, max_health, mana, max_mana, stamina, max_stamina, stat_str, stat_dex, stat_int, stat_wis, stat_con, stat_cha, stat_lck)
 		VALUES
 			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, ch.Name, ch.temporaryHash, 0, RoomLimbo, ch.Race.Id, ch.Job.Id, ch.Level, ch.Gold, ch.Experience, ch.Practices, ch.Health, ch.MaxHealth, ch.Mana, ch.MaxMana, ch.Stamina, ch.MaxStamina, ch.Stats[STAT_STRENGTH], ch.Stats[STAT_DEXTERITY], ch.Stats[STAT_INTELLIGENCE], ch.Stats[STAT_WISDOM], ch.Stats[STAT_CONSTITUTION], ch.Stats[STAT_CHARISMA], ch.Stats[STAT_LUCK])
-	ch.temporaryHash = ""
+	`, ch.AccountId, ch.Name, 0, RoomLimbo, ch.Race.Id, ch.Job.Id, ch.Level, ch.Gold, ch.Experience, ch.Practices, ch.Health, ch.MaxHealth, ch.Mana, ch.MaxMana, ch.Stamina, ch.MaxStamina, ch.Stats[STAT_STRENGTH], ch.Stats[STAT_DEXTERITY], ch.Stats[STAT_INTELLIGENCE], ch.Stats[STAT_WISDOM], ch.Stats[STAT_CONSTITUTION], ch.Stats[STAT_CHARISMA], ch.Stats[STAT_LUCK])
 	if err != nil {
 		log.Printf("Failed to finalize new character: %v.\r\n", err)
 		return err
@@ -311,6 +348,10 @@ func (ch *Character) Finalize() error {
 }
 
 func (ch *Character) SavePlayerSkills() error {
+	return ch.savePlayerSkills(ch.Game.db)
+}
+
+func (ch *Character) savePlayerSkills(exec SQLExecutor) error {
 	var proficiencyValues strings.Builder
 
 	if len(ch.Skills) == 0 {
@@ -322,7 +363,7 @@ func (ch *Character) SavePlayerSkills() error {
 	}
 
 	proficiencyValuesString := strings.TrimRight(proficiencyValues.String(), ",")
-	_, err := ch.Game.db.Exec(fmt.Sprintf(`
+	_, err := exec.Exec(fmt.Sprintf(`
 	INSERT INTO
 		pc_skill_proficiency (id, player_character_id, skill_id, job_id, proficiency)
 	VALUES
@@ -394,16 +435,42 @@ func FindStatByName(statName string) int {
 }
 
 func (ch *Character) Save() bool {
-	if ch.Client == nil || ch.Game == nil {
+	if ch.Game == nil || ch.Flags&CHAR_IS_PLAYER == 0 {
 		/* If somehow an NPC were to try to save, do not allow it. */
 		return false
 	}
 
+	tx, err := ch.Game.db.Begin()
+	if err != nil {
+		log.Printf("Failed to begin saving character: %v.\r\n", err)
+		return false
+	}
+
+	err = ch.persist(tx)
+	if err != nil {
+		log.Printf("Failed to save character %s: %v.\r\n", ch.Name, err)
+		tx.Rollback()
+		return false
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Failed to commit character %s: %v.\r\n", ch.Name, err)
+		return false
+	}
+
+	ch.dirty = false
+	return true
+}
+
+/* Write the character row, proficiencies and inventory through exec, which may be a transaction */
+func (ch *Character) persist(exec SQLExecutor) error {
 	var roomId uint = RoomLimbo
 	if ch.Room != nil {
 		roomId = ch.Room.Id
 	}
-	result, err := ch.Game.db.Exec(`
+
+	result, err := exec.Exec(`
 		UPDATE
 			player_characters
 		SET
@@ -433,29 +500,25 @@ func (ch *Character) Save() bool {
 			id = ?
 	`, ch.Wizard, roomId, ch.Race.Id, ch.Job.Id, ch.Level, ch.Gold, ch.Experience, ch.Practices, ch.Health, ch.MaxHealth, ch.Mana, ch.MaxMana, ch.Stamina, ch.MaxStamina, ch.Stats[STAT_STRENGTH], ch.Stats[STAT_DEXTERITY], ch.Stats[STAT_INTELLIGENCE], ch.Stats[STAT_WISDOM], ch.Stats[STAT_CONSTITUTION], ch.Stats[STAT_CHARISMA], ch.Stats[STAT_LUCK], ch.Id)
 	if err != nil {
-		log.Printf("Failed to save character: %v.\r\n", err)
-		return false
+		return err
 	}
 
 	_, err = result.RowsAffected()
 	if err != nil {
-		log.Printf("Failed to retrieve number of rows affected: %v.\r\n", err)
-		return false
+		return fmt.Errorf("failed to retrieve number of rows affected: %v", err)
 	}
 
-	err = ch.SavePlayerSkills()
+	err = ch.savePlayerSkills(exec)
 	if err != nil {
-		log.Printf("Failed to save player proficiencies: %v.\r\n", err)
-		return false
+		return fmt.Errorf("failed to save player proficiencies: %v", err)
 	}
 
-	err = ch.Game.SavePlayerInventory(ch)
+	err = ch.Game.savePlayerInventory(exec, ch)
 	if err != nil {
-		log.Printf("Failed to save player inventory: %v.\r\n", err)
-		return false
+		return fmt.Errorf("failed to save player inventory: %v", err)
 	}
 
-	return true
+	return nil
 }
 
 func (ch *Character) AttachObject(obj *ObjectInstance) error {
@@ -487,6 +550,8 @@ func (ch *Character) AttachObject(obj *ObjectInstance) error {
 		return err
 	}
 
+	ch.markDirty()
+
 	return nil
 }
 
@@ -542,10 +607,29 @@ func (ch *Character) DetachObject(obj *ObjectInstance) error {
 		return err
 	}
 
+	ch.markDirty()
 	return nil
 }
 
 func (game *Game) SavePlayerInventory(ch *Character) error {
+	/* Begin a transaction for bulk upsert */
+	tx, err := game.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = game.savePlayerInventory(tx, ch)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (game *Game) savePlayerInventory(exec SQLExecutor, ch *Character) error {
+	var err error
+
 	/* Object instances whose records have dirtied */
 	var updating []*ObjectInstance = make([]*ObjectInstance, 0)
 
@@ -565,20 +649,13 @@ func (game *Game) SavePlayerInventory(ch *Character) error {
 		updating = append(updating, obj)
 	}
 
-	/* Create a context and begin a transaction for bulk upsert */
-	ctx := context.Background()
-	tx, err := game.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
 	for _, obj := range updating {
 		if obj.Id == 0 {
 			continue
 		}
 
 		if obj.Inside != nil {
-			_, err = tx.ExecContext(ctx, `
+			_, err = exec.Exec(`
 				UPDATE
 					object_instances
 				SET
@@ -597,7 +674,7 @@ func (game *Game) SavePlayerInventory(ch *Character) error {
 					id = ?
 			`, obj.Name, obj.ShortDescription, obj.LongDescription, obj.Description, obj.WearLocation, obj.Flags, obj.Value0, obj.Value1, obj.Value2, obj.Value3, obj.Inside.Id, obj.Id)
 		} else {
-			_, err = tx.ExecContext(ctx, `
+			_, err = exec.Exec(`
 				UPDATE
 					object_instances
 				SET
@@ -618,17 +695,10 @@ func (game *Game) SavePlayerInventory(ch *Character) error {
 		}
 
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-
 	return nil
 }
 
@@ -751,6 +821,7 @@ func (game *Game) FindPlayerByName(username string) (*Character, *Room, error) {
 	row := game.db.QueryRow(`
 		SELECT
 			id,
+			account_id,
 			username,
 			wizard,
 			room_id,
@@ -788,7 +859,7 @@ func (game *Game) FindPlayerByName(username string) (*Character, *Room, error) {
 	var raceId uint
 	var jobId uint
 
-	err := row.Scan(&ch.Id, &ch.Name, &ch.Wizard, &roomId, &raceId, &jobId, &ch.Level, &ch.Gold, &ch.Experience, &ch.Practices, &ch.Health, &ch.MaxHealth, &ch.Mana, &ch.MaxMana, &ch.Stamina, &ch.MaxStamina, &ch.Stats[STAT_STRENGTH], &ch.Stats[STAT_DEXTERITY], &ch.Stats[STAT_INTELLIGENCE], &ch.Stats[STAT_WISDOM], &ch.Stats[STAT_CONSTITUTION], &ch.Stats[STAT_CHARISMA], &ch.Stats[STAT_LUCK])
+	err := row.Scan(&ch.Id, &ch.AccountId, &ch.Name, &ch.Wizard, &roomId, &raceId, &jobId, &ch.Level, &ch.Gold, &ch.Experience, &ch.Practices, &ch.Health, &ch.MaxHealth, &ch.Mana, &ch.MaxMana, &ch.Stamina, &ch.MaxStamina, &ch.Stats[STAT_STRENGTH], &ch.Stats[STAT_DEXTERITY], &ch.Stats[STAT_INTELLIGENCE], &ch.Stats[STAT_WISDOM], &ch.Stats[STAT_CONSTITUTION], &ch.Stats[STAT_CHARISMA], &ch.Stats[STAT_LUCK])
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -823,14 +894,76 @@ func (game *Game) FindPlayerByName(username string) (*Character, *Room, error) {
 		return nil, nil, err
 	}
 
+	err = ch.LoadPlayerChannels()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = ch.LoadPlayerIgnores()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = ch.LoadPlayerAliases()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return ch, room, nil
 }
 
+/* Usable screen width in columns, from NAWS if the client reported one */
+func (ch *Character) screenWidth() int {
+	if ch.Client == nil {
+		return DefaultScreenWidth
+	}
+
+	width, _ := ch.Client.windowSize()
+	if width <= 0 {
+		return DefaultScreenWidth
+	}
+
+	if width < MinimumScreenWidth {
+		return MinimumScreenWidth
+	}
+
+	return width
+}
+
+/* Usable screen height in rows, or zero if the client never told us */
+func (ch *Character) screenHeight() int {
+	if ch.Client == nil {
+		return 0
+	}
+
+	_, height := ch.Client.windowSize()
+	if height <= 0 {
+		return 0
+	}
+
+	if height < MinimumScreenHeight {
+		return MinimumScreenHeight
+	}
+
+	return height
+}
+
+/* Lines of output per page before the pager waits for input */
+func (ch *Character) pageLength() int {
+	height := ch.screenHeight()
+	if height == 0 {
+		return DefaultMaxLines
+	}
+
+	/* Leave room for the pager's continuation line and the prompt */
+	return height - 2
+}
+
 func (ch *Character) clearOutputBuffer() {
 	ch.output = make([]byte, 32768)
 	ch.outputHead = 0
 	ch.outputCursor = 0
-	ch.inputCursor = DefaultMaxLines
+	ch.inputCursor = ch.pageLength()
 }
 
 func (ch *Character) flushOutput() {
@@ -840,7 +973,7 @@ func (ch *Character) flushOutput() {
 
 	var page bytes.Buffer
 	var lines []string
-	var maxLines int = DefaultMaxLines
+	var maxLines int = ch.pageLength()
 
 	scan := bufio.NewScanner(strings.NewReader(string(ch.output)))
 	scan.Split(func(data []byte, eof bool) (advance int, token []byte, err error) {
@@ -912,6 +1045,7 @@ func (ch *Character) gainExperience(experience int) {
 	if ch.Level < LevelHero {
 		ch.Send(fmt.Sprintf("{WYou gained %d experience points.{x\r\n", experience))
 		ch.Experience = ch.Experience + uint(experience)
+		ch.markDirty()
 
 		/* If we gain enough experience to level up multiple times */
 		for {
@@ -1050,7 +1184,20 @@ func (ch *Character) getLongDescription(viewer *Character) string {
 	}
 
 	if ch.Flags&CHAR_IS_PLAYER != 0 {
-		return fmt.Sprintf("%s is here.", ch.Name)
+		if ch.isLinkdead() {
+			return fmt.Sprintf("{D[LINKDEAD]{x %s %s", ch.Name, ch.positionDescription(viewer))
+		}
+
+		if ch.Afk != nil {
+			return fmt.Sprintf("{G[AFK]{x %s %s", ch.Name, ch.positionDescription(viewer))
+		}
+
+		return fmt.Sprintf("%s %s", ch.Name, ch.positionDescription(viewer))
+	}
+
+	/* A mobile's long description has it standing about, so only use it when it is */
+	if ch.Position != PositionStanding {
+		return fmt.Sprintf("%s %s", ch.GetShortDescriptionUpper(viewer), ch.positionDescription(viewer))
 	}
 
 	return ch.LongDescription
@@ -1058,6 +1205,7 @@ func (ch *Character) getLongDescription(viewer *Character) string {
 
 func (ch *Character) AddObject(obj *ObjectInstance) {
 	ch.Inventory.Insert(obj)
+	ch.markDirty()
 
 	obj.CarriedBy = ch
 	obj.InRoom = nil
@@ -1066,6 +1214,7 @@ func (ch *Character) AddObject(obj *ObjectInstance) {
 
 func (ch *Character) RemoveObject(obj *ObjectInstance) {
 	ch.Inventory.Remove(obj)
+	ch.markDirty()
 
 	obj.CarriedBy = nil
 	obj.Inside = nil
@@ -1198,7 +1347,7 @@ func (game *Game) Broadcast(message string, filter goja.Callable) {
 }
 
 func WiznetBroadcastFilter(ch *Character) bool {
-	return ch.Wiznet
+	return ch.isListening(ch.Game.findChannel(WiznetChannelName))
 }
 
 func (game *Game) broadcast(message string, filterFn func(*Character) bool) {
@@ -1239,9 +1388,11 @@ func NewCharacter() *Character {
 	character.Room = nil
 	character.Trail = make([]*Room, 0)
 	character.PlaneIndex = nil
-	character.Wiznet = false
+	character.channels = make(map[string]bool)
+	character.ignoring = make(map[int]string)
+	character.aliases = make(map[string]string)
 	character.Practices = 0
-	character.Position = PositionDead
+	character.Position = PositionStanding
 	character.output = make([]byte, 65536)
 	character.outputCursor = 0
 	character.inputCursor = DefaultMaxLines
@@ -8,45 +8,17 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/dop251/goja"
 )
 
-/*
- * Telnet reference used: http://pcmicro.com/netfoss/telnet.html
- */
-
-/* OOB telnet messaging */
-const (
-	TelnetECHO                 = 1
-	TelnetSUPPRESSGOAHEAD      = 3
-	TelnetTERMINALTYPE         = 24
-	TelnetWINDOWSIZE           = 31
-	TelnetTS                   = 32
-	TelnetENVIRONMENTVARIABLES = 36
-	TelnetNEWENVIRONMENT       = 39
-
-	TelnetERASELINE = 248
-	TelnetWILL      = 251
-	TelnetWONT      = 252
-	TelnetDO        = 253
-	TelnetDONT      = 254
-	TelnetIAC       = 255
-)
-
-type TelnetCommand struct {
-	opCodes []byte
-}
-
 /* App-level connection state */
 const (
 	ConnectionStateNone            = 0
@@ -60,22 +32,42 @@ const (
 	ConnectionStateChooseClass     = 8
 	ConnectionStateConfirmClass    = 9
 	ConnectionStateRollingStats    = 10
+	ConnectionStateConfirmAccount  = 11
+	ConnectionStateChooseCharacter = 12
+	ConnectionStateNewCharacter    = 13
 	ConnectionStateMessageOfTheDay = 23
 	ConnectionStatePlaying         = 24
 	ConnectionStateMax             = 25
 )
 
+/* A message for the write pump, which may also have it start compressing everything after */
+type ClientOutput struct {
+	data             []byte
+	startCompression bool
+}
+
 /* Instance of a client connection */
 type Client struct {
 	id                string
 	sessionStartedAt  time.Time
 	conn              net.Conn
 	ansiEnabled       bool
-	send              chan []byte
+	send              chan ClientOutput
 	close             chan bool
+	writerDone        chan bool
 	remainingRolls    int
 	delayMutex        sync.Mutex
 	delayUntil        time.Time
+	telnetMutex       sync.Mutex
+	telnetOptions     map[byte]*TelnetOptionState
+	terminalType      string
+	windowWidth       int
+	windowHeight      int
+	gmcp              GMCPState
+	compressor        *zlib.Writer
+	account           *Account
+	rateLimitExempt   int32
+	lastInputAt       time.Time
 	Character         *Character     `json:"character"`
 	ConnectionState   uint           `json:"connectionState"`
 	ConnectionHandler *goja.Callable `json:"connectionHandler"`
@@ -87,162 +79,70 @@ type ClientTextMessage struct {
 }
 
 func (client *Client) readPump(game *Game) {
-	defer func() {
-		client.conn.Close()
-	}()
+	input := make(chan string, Config.RateLimitConfiguration.QueueLength)
+	stop := make(chan bool)
+	stopped := make(chan bool)
+	closedByPeer := false
 
-	reader := bufio.NewReader(client.conn)
+	go client.dispatchInput(game, input, stop, stopped)
 
-	for {
-		firstByte, err := reader.Peek(1)
-		if err != nil {
-			if err == io.EOF {
-				game.unregister <- client
-				return
-			}
+	defer func() {
+		/* Drop anything still queued rather than run it for a departed client */
+		close(stop)
+		<-stopped
 
-			log.Printf("Unable to read first byte: %v.\r\n", err)
-			return
+		if closedByPeer {
+			game.unregister <- client
 		}
 
-		var commands []*TelnetCommand = []*TelnetCommand{}
-
-		clientRequests := make([]byte, 0) /* IAC DO operation */
-		clientWill := make([]byte, 0)     /* IAC WILL operation */
-
-		if firstByte[0] == TelnetIAC {
-			var nextByte []byte
-			var length int = 3
-
-			nextByte, err = reader.Peek(2)
-			if nil != err {
-				log.Printf("Unable to peek next byte after IAC: %v.\r\n", err)
-				return
-			}
-
-			switch nextByte[0] {
-			case TelnetDONT:
-				length = 3
-				requestOption, err := reader.Peek(3)
-				if err != nil {
-					log.Printf("Unable to peek next 2 bytes for IAC DONT: %v.\r\n", err)
-					break
-				}
-
-				log.Printf("Client sent DONT %d.\r\n", requestOption[2])
-			case TelnetWILL:
-				length = 3
-
-				willOption, err := reader.Peek(3)
-				if err != nil {
-					log.Printf("Unable to peek next 2 bytes for IAC WILL: %v.\r\n", err)
-					break
-				}
-
-				clientWill = append(clientWill, willOption[2])
-			case TelnetDO:
-				length = 3
+		client.conn.Close()
+	}()
 
-				requestOption, err := reader.Peek(3)
-				if err != nil {
-					log.Printf("Unable to peek next 2 bytes for IAC DO: %v.\r\n", err)
-					break
-				}
+	discarding := false
 
-				clientRequests = append(clientWill, requestOption[2])
+	parser := NewTelnetParser()
+	parser.OnNegotiation = client.handleNegotiation
+	parser.OnSubnegotiation = client.handleSubnegotiation
+	parser.OnLine = func(line []byte) {
+		if client.queueInput(input, string(line)) {
+			discarding = false
+			return
+		}
 
-			/*
-			 * To fix: I believe we are now only grabbing the first IAC command each time now - it still seems
-			 * to passively work out, but should instead recursively peek ahead here for all commands at once.
-			 */
-			case TelnetIAC:
-				break
-			default:
-				log.Printf("Unknown IAC code: %d.\r\n", nextByte[0])
-			}
+		if !discarding {
+			discarding = true
+			client.Send([]byte(client.TranslateColourCodes(CommandsDiscardedMessage)))
+		}
+	}
 
-			reader.Discard(length)
-		} else {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				log.Printf("Failed to read string from reader: %v.\r\n", err)
-				break
-			}
+	buf := make([]byte, 4096)
 
-			if len(line) > 512 {
+	for {
+		n, err := client.conn.Read(buf)
+		if n > 0 {
+			parseErr := parser.Parse(buf[:n])
+			if parseErr == ErrTelnetLineTooLong {
 				log.Printf("Client line input was too long, dropping connection.\r\n")
 				return
 			}
-
-			trimmed := strings.TrimRight(line, "\r\n")
-
-			clientMessage := ClientTextMessage{
-				client:  client,
-				message: trimmed,
-			}
-
-			client.delayMutex.Lock()
-			delay := client.delayUntil
-			client.delayMutex.Unlock()
-
-			if time.Now().Before(delay) {
-				<-time.After(time.Duration(time.Until(delay)))
-			}
-
-			game.clientMessage <- clientMessage
 		}
 
-		for _, command := range commands {
-			if len(command.opCodes) < 3 {
-				/* We can't make safe assumptions about this IAC command; skip. */
-				continue
-			}
-
-			intent := command.opCodes[1]
-			switch intent {
-			case TelnetWILL:
-				clientWill = append(clientWill, command.opCodes[2])
-			case TelnetDO:
-				clientRequests = append(clientRequests, command.opCodes[2])
+		if err != nil {
+			if err == io.EOF {
+				closedByPeer = true
+				return
 			}
-		}
-
-		/*
-		 * For every WILL/DO message the client has sent via telnet, we're going to respond
-		 * with the appropriate "not supported, disable it" message until we incrementally
-		 * add those protocol features.
-		 */
-		var telnetResponse bytes.Buffer
-
-		for _, will := range clientWill {
-			telnetResponse.Write([]byte{
-				TelnetIAC,
-				TelnetDONT,
-				will,
-			})
-		}
-
-		for _, do := range clientRequests {
-			telnetResponse.Write([]byte{
-				TelnetIAC,
-				TelnetWONT,
-				do,
-			})
-		}
 
-		/* Only send this if necessary! */
-		if telnetResponse.Len() > 0 {
-			responseBytes := telnetResponse.Bytes()
-
-			if client.Send(responseBytes) {
-				break
-			}
+			log.Printf("Failed to read from client: %v.\r\n", err)
+			return
 		}
 	}
 }
 
 func (client *Client) writePump(game *Game) {
 	defer func() {
+		client.endCompression()
+		close(client.writerDone)
 		close(client.send)
 
 		game.unregister <- client
@@ -254,7 +154,7 @@ func (client *Client) writePump(game *Game) {
 			return
 
 		case outgoing := <-client.send:
-			_, err := client.conn.Write(outgoing)
+			err := client.writeOutput(outgoing.data, outgoing.startCompression)
 			if err != nil {
 				log.Printf("Error writing to socket: %v\r\n", err)
 				return
@@ -264,13 +164,17 @@ func (client *Client) writePump(game *Game) {
 }
 
 func (client *Client) Send(data []byte) (closed bool) {
+	return client.queueOutput(ClientOutput{data: data})
+}
+
+func (client *Client) queueOutput(output ClientOutput) (closed bool) {
 	defer func() {
 		if recover() != nil {
 			closed = true
 		}
 	}()
 
-	client.send <- data
+	client.send <- output
 	return false
 }
 
@@ -282,12 +186,24 @@ func (game *Game) checkReconnect(client *Client, name string) bool {
 			client.Character = nil
 			ch.Client = client
 
+			awaySince := ch.linkdeadAt
+			ch.linkdeadAt = time.Time{}
+
 			client.Character = ch
 			client.ConnectionState = ConnectionStatePlaying
 
 			ch.clearOutputBuffer()
 			ch.Send("{MReconnecting to a session in progress.{x\r\n")
 
+			if !awaySince.IsZero() {
+				ch.showMissedChannelMessages(awaySince)
+			}
+
+			if ch.Afk == nil {
+				ch.replayQueuedTells()
+			}
+			game.InvokeNamedEventHandlersWithContextAndArguments("playerEnter", game.vm.ToValue(game), game.vm.ToValue(ch))
+
 			if ch.Room != nil {
 				for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
 					character := iter.Value.(*Character)
@@ -311,25 +227,57 @@ func (client *Client) Delay(ms int) {
 	client.delayMutex.Unlock()
 }
 
-func (game *Game) handleConnection(conn net.Conn) {
-	defer func() {
-		recover()
-	}()
+/*
+ * Stop the write pump once everything already queued has been written, ending any
+ * compression stream, without closing the connection itself.
+ */
+func (client *Client) stopWriting(timeout time.Duration) {
+	select {
+	case client.close <- true:
+	case <-client.writerDone:
+		return
+	case <-time.After(timeout):
+		return
+	}
+
+	select {
+	case <-client.writerDone:
+	case <-time.After(timeout):
+	}
+}
 
+func NewClient(conn net.Conn) *Client {
 	client := &Client{sessionStartedAt: time.Now()}
 	client.conn = conn
-	client.send = make(chan []byte)
+	client.send = make(chan ClientOutput)
 	client.close = make(chan bool)
+	client.writerDone = make(chan bool)
 	client.Character = nil
 	client.remainingRolls = 10
 	client.ConnectionState = ConnectionStateNone
 	client.delayUntil = time.Now()
+	client.lastInputAt = time.Now()
 	client.delayMutex = sync.Mutex{}
 	client.ansiEnabled = true
+	client.telnetOptions = make(map[byte]*TelnetOptionState)
+
+	return client
+}
+
+func (game *Game) handleConnection(conn net.Conn) {
+	defer func() {
+		recover()
+	}()
+
+	client := NewClient(conn)
 
 	/* Spawn two goroutines to handle client I/O */
 	go client.readPump(game)
 	go client.writePump(game)
 
+	if !client.isWebSocket() {
+		client.startNegotiation()
+	}
+
 	game.register <- client
 }
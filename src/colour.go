@@ -8,7 +8,10 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"regexp"
+	"strconv"
 )
 
 const AnsiBoldRed = "\u001b[31;1m"
@@ -123,6 +126,15 @@ func (client *Client) TranslateColourCodes(s string) string {
 	return input
 }
 
+/* Remove colour codes entirely, for consumers which aren't terminals */
+func StripColourCodes(s string) string {
+	for colour := range AnsiColourCodeTable {
+		s = AnsiColourCodeTable[colour].regularExpression.ReplaceAllString(s, "")
+	}
+
+	return s
+}
+
 func SeverityColourFromPercentage(percentage int) string {
 	if percentage < 10 {
 		return "{D"
@@ -140,3 +152,120 @@ func SeverityColourFromPercentage(percentage int) string {
 
 	return "{G"
 }
+
+/*
+ * Converts ANSI SGR colour sequences to HTML spans for browser clients.  Colour state
+ * carries over between calls, since output is written in arbitrary pieces; every call
+ * produces balanced markup.  Other escape sequences (cursor movement, etc.) are dropped.
+ */
+type AnsiHTMLConverter struct {
+	foreground int
+	bold       bool
+}
+
+func NewAnsiHTMLConverter() *AnsiHTMLConverter {
+	return &AnsiHTMLConverter{}
+}
+
+func (converter *AnsiHTMLConverter) openSpan(buf *bytes.Buffer) bool {
+	if converter.foreground == 0 && !converter.bold {
+		return false
+	}
+
+	buf.WriteString("<span class=\"")
+	if converter.foreground != 0 {
+		buf.WriteString(fmt.Sprintf("ansi-%d", converter.foreground))
+	}
+
+	if converter.bold {
+		if converter.foreground != 0 {
+			buf.WriteString(" ")
+		}
+
+		buf.WriteString("ansi-bold")
+	}
+
+	buf.WriteString("\">")
+	return true
+}
+
+func (converter *AnsiHTMLConverter) applyParameters(parameters []byte) {
+	for _, parameter := range bytes.Split(parameters, []byte(";")) {
+		code, err := strconv.Atoi(string(parameter))
+		if err != nil {
+			/* An empty parameter means reset */
+			code = 0
+		}
+
+		switch {
+		case code == 0:
+			converter.foreground = 0
+			converter.bold = false
+		case code == 1:
+			converter.bold = true
+		case code == 22:
+			converter.bold = false
+		case code >= 30 && code <= 37:
+			converter.foreground = code
+		case code == 39:
+			converter.foreground = 0
+		}
+	}
+}
+
+func (converter *AnsiHTMLConverter) Convert(data []byte) []byte {
+	var buf bytes.Buffer
+
+	spanOpen := converter.openSpan(&buf)
+
+	for index := 0; index < len(data); index++ {
+		b := data[index]
+
+		switch b {
+		case 0x1b:
+			if index+1 >= len(data) || data[index+1] != '[' {
+				continue
+			}
+
+			/* Find the final byte of the control sequence */
+			end := index + 2
+			for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+				end++
+			}
+
+			if end >= len(data) {
+				index = end
+				continue
+			}
+
+			if data[end] == 'm' {
+				converter.applyParameters(data[index+2 : end])
+
+				if spanOpen {
+					buf.WriteString("</span>")
+				}
+
+				spanOpen = converter.openSpan(&buf)
+			}
+
+			index = end
+		case '\r':
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '"':
+			buf.WriteString("&quot;")
+		default:
+			buf.WriteByte(b)
+		}
+	}
+
+	if spanOpen {
+		buf.WriteString("</span>")
+	}
+
+	return buf.Bytes()
+}
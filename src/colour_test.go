@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"testing"
+)
+
+type ansiHTMLTest struct {
+	input, expectedOutput string
+}
+
+var ansiHTMLTests = []ansiHTMLTest{
+	{
+		"plain <text> & more\r\n",
+		"plain &lt;text&gt; &amp; more\n",
+	},
+	{
+		AnsiBoldRed + "danger" + AnsiReset + " ok",
+		`<span class="ansi-31 ansi-bold">danger</span> ok`,
+	},
+	{
+		AnsiGreen + "grass",
+		`<span class="ansi-32">grass</span>`,
+	},
+	{
+		"\u001b[2Jcleared",
+		"cleared",
+	},
+}
+
+func TestAnsiHTMLConverter(t *testing.T) {
+	for _, test := range ansiHTMLTests {
+		output := string(NewAnsiHTMLConverter().Convert([]byte(test.input)))
+
+		if output != test.expectedOutput {
+			t.Errorf("Convert(%q) = %q, expected %q", test.input, output, test.expectedOutput)
+		}
+	}
+}
+
+func TestAnsiHTMLConverterCarriesState(t *testing.T) {
+	converter := NewAnsiHTMLConverter()
+	converter.Convert([]byte(AnsiCyan + "first"))
+
+	output := string(converter.Convert([]byte("second")))
+	if output != `<span class="ansi-36">second</span>` {
+		t.Errorf("colour did not carry over between writes: %q", output)
+	}
+}
@@ -0,0 +1,451 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+/* How a single attack turned out */
+const (
+	HitResultMiss     = 0
+	HitResultDodge    = 1
+	HitResultTumble   = 2
+	HitResultParry    = 3
+	HitResultBlock    = 4
+	HitResultHit      = 5
+	HitResultCritical = 6
+)
+
+/*
+ * One attack in a combat round.  The engine rolls it in full before handing it to any
+ * onBeforeHit handlers, which may change its result, damage or damage type, or return
+ * false to call the attack off entirely; onAfterHit handlers then see what happened.
+ */
+type Hit struct {
+	Attacker   *Character      `json:"attacker"`
+	Victim     *Character      `json:"victim"`
+	Weapon     *ObjectInstance `json:"weapon"`
+	Noun       string          `json:"noun"`
+	Result     int             `json:"result"`
+	Damage     int             `json:"damage"`
+	DamageType int             `json:"damageType"`
+}
+
+func (hit *Hit) Landed() bool {
+	return hit.Result >= HitResultHit
+}
+
+/* The damage message verb for hits up to Maximum damage */
+type DamageTier struct {
+	Maximum     int
+	Verb        string
+	Punctuation string
+}
+
+var DamageTierTable = []DamageTier{
+	{Maximum: 0, Verb: "misses", Punctuation: "."},
+	{Maximum: 2, Verb: "scratches", Punctuation: "."},
+	{Maximum: 4, Verb: "grazes", Punctuation: "."},
+	{Maximum: 6, Verb: "hits", Punctuation: "."},
+	{Maximum: 10, Verb: "injures", Punctuation: "."},
+	{Maximum: 14, Verb: "wounds", Punctuation: "."},
+	{Maximum: 18, Verb: "decimates", Punctuation: "!"},
+	{Maximum: 22, Verb: "devastates", Punctuation: "!"},
+	{Maximum: 26, Verb: "maims", Punctuation: "!"},
+	{Maximum: 30, Verb: "MAULS", Punctuation: "!"},
+	{Maximum: 36, Verb: "MUTILATES", Punctuation: "!"},
+	{Maximum: 42, Verb: "DISEMBOWELS", Punctuation: "!"},
+	{Maximum: 50, Verb: "DISMEMBERS", Punctuation: "!"},
+	{Maximum: 60, Verb: "MASSACRES", Punctuation: "!"},
+	{Maximum: 75, Verb: "MANGLES", Punctuation: "!"},
+	{Maximum: 90, Verb: "*** DEMOLISHES ***", Punctuation: "!"},
+	{Maximum: 110, Verb: "=== OBLITERATES ===", Punctuation: "!"},
+	{Maximum: 140, Verb: ">>> ANNIHILATES <<<", Punctuation: "!"},
+	{Maximum: math.MaxInt32, Verb: "does UNSPEAKABLE things to", Punctuation: "!"},
+}
+
+/* What an attack of each damage type is called in combat messages */
+var DamageTypeNounTable map[int]string = map[int]string{
+	DamageTypeBash:     "blow",
+	DamageTypeSlash:    "slash",
+	DamageTypeStab:     "stab",
+	DamageTypeExotic:   "blast",
+	DamageTypeFire:     "flame",
+	DamageTypeCold:     "chill",
+	DamageTypeShock:    "shock",
+	DamageTypePoison:   "sting",
+	DamageTypeAcid:     "acid",
+	DamageTypeHoly:     "smite",
+	DamageTypeNegative: "drain",
+}
+
+const UnarmedAttackNoun = "punch"
+
+func damageNoun(damageType int) string {
+	noun, ok := DamageTypeNounTable[damageType]
+	if !ok {
+		return "attack"
+	}
+
+	return noun
+}
+
+func damageTier(amount int) DamageTier {
+	for _, tier := range DamageTierTable {
+		if amount <= tier.Maximum {
+			return tier
+		}
+	}
+
+	return DamageTierTable[len(DamageTierTable)-1]
+}
+
+/* Percent chance an attack of accuracy lands against defense, never certain either way */
+func hitChance(accuracy int, defense int) int {
+	chance := 80 + 3*(accuracy-defense)
+
+	if chance < 5 {
+		return 5
+	}
+
+	if chance > 95 {
+		return 95
+	}
+
+	return chance
+}
+
+/* Percent chance a landed attack is a critical hit, which does double damage */
+func criticalChance(luck int) int {
+	chance := 5 + (luck-10)/2
+
+	if chance < 1 {
+		return 1
+	}
+
+	if chance > 20 {
+		return 20
+	}
+
+	return chance
+}
+
+func (ch *Character) attackAccuracy() int {
+	dexterity, _ := ch.GetStat(STAT_DEXTERITY)
+
+	return int(ch.Level) + (dexterity - 10)
+}
+
+func (ch *Character) defenseRating() int {
+	dexterity, _ := ch.GetStat(STAT_DEXTERITY)
+
+	return int(ch.Level) + (dexterity - 10) + ch.Defense
+}
+
+/* One attack, plus one for every four points of dexterity over ten and another while hasted */
+func (ch *Character) attackRounds() int {
+	dexterity, _ := ch.GetStat(STAT_DEXTERITY)
+
+	rounds := 1 + (dexterity-10)/4
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	if ch.Affected&AFFECT_HASTE != 0 {
+		rounds++
+	}
+
+	return rounds
+}
+
+/* Passive defensive skills succeed a fifth of the time at full proficiency */
+func (ch *Character) defensiveSkillRoll(name string) bool {
+	proficiency := ch.FindProficiencyByName(name)
+
+	return proficiency != nil && rand.Intn(500) < proficiency.Proficiency
+}
+
+func (ch *Character) rollDamage(weapon *ObjectInstance) int {
+	if weapon == nil {
+		strength, _ := ch.GetStat(STAT_STRENGTH)
+
+		damage := rand.Intn(2)
+		if strength >= 3 {
+			damage += rand.Intn(strength / 3)
+		}
+
+		/* +1 damage to unarmed base damage for every 10% of unarmed combat proficiency */
+		unarmedCombatProficiency := ch.FindProficiencyByName("unarmed combat")
+		if unarmedCombatProficiency != nil {
+			damage += unarmedCombatProficiency.Proficiency / 10
+		}
+
+		return damage
+	}
+
+	/* Weapons roll value0 dice of value1 sides, counted from zero, plus value2 */
+	var sum int = 0
+
+	if weapon.Value1 > 0 {
+		for i := 0; i < weapon.Value0; i++ {
+			sum += rand.Intn(weapon.Value1)
+		}
+	}
+
+	return sum + weapon.Value2
+}
+
+/* Roll an attack from ch against victim through to its final damage */
+func (game *Game) rollHit(ch *Character, victim *Character) *Hit {
+	hit := &Hit{
+		Attacker:   ch,
+		Victim:     victim,
+		Weapon:     ch.GetEquipment(WearLocationWielded),
+		Noun:       UnarmedAttackNoun,
+		Result:     HitResultMiss,
+		DamageType: DamageTypeBash,
+	}
+
+	if hit.Weapon != nil {
+		hit.DamageType = hit.Weapon.Value3
+		hit.Noun = damageNoun(hit.DamageType)
+	}
+
+	if rand.Intn(100) >= hitChance(ch.attackAccuracy(), victim.defenseRating()) {
+		return hit
+	}
+
+	switch {
+	case victim.defensiveSkillRoll("dodge"):
+		hit.Result = HitResultDodge
+		return hit
+
+	case victim.defensiveSkillRoll("acrobatics"):
+		hit.Result = HitResultTumble
+		return hit
+
+	case victim.GetEquipment(WearLocationWielded) != nil && victim.defensiveSkillRoll("parry"):
+		hit.Result = HitResultParry
+		return hit
+
+	case victim.GetEquipment(WearLocationShield) != nil && victim.defensiveSkillRoll("shield block"):
+		hit.Result = HitResultBlock
+		return hit
+	}
+
+	luck, _ := ch.GetStat(STAT_LUCK)
+
+	hit.Result = HitResultHit
+	hit.Damage = ch.rollDamage(hit.Weapon)
+
+	if rand.Intn(100) < criticalChance(luck) {
+		hit.Result = HitResultCritical
+		hit.Damage *= 2
+	}
+
+	armor := victim.GetArmorValues()
+	if hit.DamageType >= 0 && hit.DamageType < len(armor) {
+		hit.Damage -= armor[hit.DamageType] / 4
+	}
+
+	if hit.Damage < 0 {
+		hit.Damage = 0
+	}
+
+	hit.Damage = victim.modifyDamage(hit.DamageType, hit.Damage)
+
+	return hit
+}
+
+/* Tell the attacker, victim and room how an attack went */
+func (game *Game) showHit(hit *Hit) {
+	ch := hit.Attacker
+	victim := hit.Victim
+
+	switch hit.Result {
+	case HitResultDodge:
+		ch.Send(fmt.Sprintf("{D%s{D dodges out of the way of your attack!{x\r\n", victim.GetShortDescriptionUpper(ch)))
+		victim.Send(fmt.Sprintf("{DYou dodge an attack by %s{D!{x\r\n", ch.GetShortDescription(victim)))
+
+	case HitResultTumble:
+		ch.Send(fmt.Sprintf("{D%s{D nimbly backflips out of the way of your attack!{x\r\n", victim.GetShortDescriptionUpper(ch)))
+		victim.Send(fmt.Sprintf("{DYou nimbly backflip out of the way of %s{D's attack!{x\r\n", ch.GetShortDescription(victim)))
+
+	case HitResultParry:
+		ch.Send(fmt.Sprintf("{D%s{D parries your attack!{x\r\n", victim.GetShortDescriptionUpper(ch)))
+		victim.Send(fmt.Sprintf("{DYou parry %s{D's attack!{x\r\n", ch.GetShortDescription(victim)))
+
+	case HitResultBlock:
+		ch.Send(fmt.Sprintf("{D%s{D blocks your attack with a shield!{x\r\n", victim.GetShortDescriptionUpper(ch)))
+		victim.Send(fmt.Sprintf("{DYou block %s{D's attack with your shield!{x\r\n", ch.GetShortDescription(victim)))
+
+	case HitResultCritical:
+		ch.Send(fmt.Sprintf("{WYou strike a vital spot on %s{W!{x\r\n", victim.GetShortDescription(ch)))
+		victim.Send(fmt.Sprintf("{W%s{W strikes a vital spot!{x\r\n", ch.GetShortDescriptionUpper(victim)))
+		game.sendDamageMessages(ch, victim, hit.Noun, hit.Damage)
+
+	default:
+		game.sendDamageMessages(ch, victim, hit.Noun, hit.Damage)
+	}
+}
+
+func (game *Game) sendDamageMessages(ch *Character, victim *Character, noun string, amount int) {
+	tier := damageTier(amount)
+
+	if ch.Room != nil && victim.Room != nil && victim.Room == ch.Room {
+		for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+			character := iter.Value.(*Character)
+			if character != ch && character != victim {
+				character.Send(fmt.Sprintf("{G%s{G's %s %s %s{G%s{x\r\n",
+					ch.GetShortDescriptionUpper(character),
+					noun,
+					tier.Verb,
+					victim.GetShortDescription(character),
+					tier.Punctuation))
+			}
+		}
+	}
+
+	ch.Send(fmt.Sprintf("{GYour %s %s %s{G%s{x\r\n", noun, tier.Verb, victim.GetShortDescription(ch), tier.Punctuation))
+	victim.Send(fmt.Sprintf("{Y%s{Y's %s %s you%s{x\r\n", ch.GetShortDescriptionUpper(victim), noun, tier.Verb, tier.Punctuation))
+}
+
+/* Bring a victim, and any of their group standing with them, into the fight against ch */
+func (game *Game) defend(ch *Character, victim *Character) {
+	if victim.Room == nil || victim.Room != ch.Room {
+		return
+	}
+
+	if victim.Group == nil {
+		if victim.Fighting == nil {
+			game.StartCombat(victim, ch)
+		}
+
+		return
+	}
+
+	for iter := victim.Group.Head; iter != nil; iter = iter.Next {
+		gch := iter.Value.(*Character)
+
+		if gch.Fighting != nil || gch.Room != ch.Room {
+			continue
+		}
+
+		if gch != victim {
+			gch.Send(fmt.Sprintf("{WYou start attacking %s{W in defense of %s{W!{x\r\n", ch.GetShortDescription(gch), victim.GetShortDescription(gch)))
+		}
+
+		game.StartCombat(gch, ch)
+	}
+}
+
+func (game *Game) oneHit(ch *Character, victim *Character) {
+	hit := game.rollHit(ch, victim)
+
+	if game.hasEventHandlers("onBeforeHit") {
+		values, _ := game.InvokeNamedEventHandlersWithContextAndArguments("onBeforeHit", game.vm.ToValue(game), game.vm.ToValue(hit))
+		for _, value := range values {
+			if value != nil && value.StrictEquals(game.vm.ToValue(false)) {
+				return
+			}
+		}
+	}
+
+	if !hit.Landed() || hit.Damage < 0 {
+		hit.Damage = 0
+	}
+
+	game.showHit(hit)
+
+	if hit.Landed() {
+		game.inflictDamage(ch, victim, false, hit.Damage, hit.DamageType)
+	}
+
+	game.defend(ch, victim)
+
+	if game.hasEventHandlers("onAfterHit") {
+		game.InvokeNamedEventHandlersWithContextAndArguments("onAfterHit", game.vm.ToValue(game), game.vm.ToValue(hit))
+	}
+}
+
+/* Take every attack ch has this round, returning whether they could attack at all */
+func (game *Game) multiHit(ch *Character) bool {
+	var attacked bool = false
+
+	rounds := ch.attackRounds()
+
+	for round := 0; round < rounds; round++ {
+		victim := ch.Fighting
+
+		if victim == nil || victim.Room == nil || ch.Room == nil || victim.Room != ch.Room {
+			break
+		}
+
+		if victim.Room.Flags&ROOM_SAFE != 0 {
+			break
+		}
+
+		attacked = true
+
+		/* A stunned fighter stays in the fight but loses their attacks */
+		if ch.Affected&AFFECT_PARALYSIS != 0 {
+			break
+		}
+
+		game.oneHit(ch, victim)
+	}
+
+	return attacked
+}
+
+/*
+ * Run a round of every fight, disposing of those where nobody could attack, then let
+ * scripts act on the combatUpdate event.
+ */
+func (game *Game) combatUpdate() {
+	fights := make([]*Combat, 0, game.Fights.Count)
+
+	for iter := game.Fights.Head; iter != nil; iter = iter.Next {
+		fights = append(fights, iter.Value.(*Combat))
+	}
+
+	for _, combat := range fights {
+		var found bool = false
+
+		/* Participants may have been moved apart or extracted since the last round */
+		combat.settle()
+
+		if len(combat.Participants) < 2 {
+			game.DisposeCombat(combat)
+			continue
+		}
+
+		/* Defenders join mid-round, so walk the participants as they were when it began */
+		participants := make([]*Character, len(combat.Participants))
+		copy(participants, combat.Participants)
+
+		for _, vch := range participants {
+			if vch.Room == nil || vch.Combat != combat {
+				continue
+			}
+
+			if game.multiHit(vch) {
+				found = true
+			}
+		}
+
+		if !found {
+			game.DisposeCombat(combat)
+		}
+	}
+
+	game.InvokeNamedEventHandlersWithContextAndArguments("combatUpdate", game.vm.ToValue(game))
+}
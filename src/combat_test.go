@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+func TestDamageTierBoundaries(t *testing.T) {
+	expected := map[int]string{
+		0:    "misses",
+		1:    "scratches",
+		2:    "scratches",
+		3:    "grazes",
+		30:   "MAULS",
+		31:   "MUTILATES",
+		9999: "does UNSPEAKABLE things to",
+	}
+
+	for amount, verb := range expected {
+		if tier := damageTier(amount); tier.Verb != verb {
+			t.Errorf("expected %d damage to be described as %q, got %q", amount, verb, tier.Verb)
+		}
+	}
+}
+
+func TestDamageTiersAscend(t *testing.T) {
+	for index := 1; index < len(DamageTierTable); index++ {
+		if DamageTierTable[index].Maximum <= DamageTierTable[index-1].Maximum {
+			t.Errorf("damage tier %q does not follow %q in ascending order", DamageTierTable[index].Verb, DamageTierTable[index-1].Verb)
+		}
+	}
+}
+
+func TestHitChanceIsNeverCertain(t *testing.T) {
+	if chance := hitChance(10, 10); chance != 80 {
+		t.Errorf("expected an 80%% chance between equals, got %d", chance)
+	}
+
+	if chance := hitChance(100, 1); chance != 95 {
+		t.Errorf("expected a hit chance capped at 95%%, got %d", chance)
+	}
+
+	if chance := hitChance(1, 100); chance != 5 {
+		t.Errorf("expected a hit chance floored at 5%%, got %d", chance)
+	}
+}
+
+func TestCriticalChanceFollowsLuck(t *testing.T) {
+	if criticalChance(18) <= criticalChance(10) {
+		t.Errorf("expected more luck to mean more critical hits")
+	}
+
+	if chance := criticalChance(100); chance != 20 {
+		t.Errorf("expected a critical chance capped at 20%%, got %d", chance)
+	}
+
+	if chance := criticalChance(-100); chance != 1 {
+		t.Errorf("expected a critical chance floored at 1%%, got %d", chance)
+	}
+}
+
+func TestAttackRounds(t *testing.T) {
+	ch := NewCharacter()
+
+	ch.Stats[STAT_DEXTERITY] = 3
+	if rounds := ch.attackRounds(); rounds != 1 {
+		t.Errorf("expected a clumsy character to still attack once, got %d", rounds)
+	}
+
+	ch.Stats[STAT_DEXTERITY] = 18
+	ch.Affected |= AFFECT_HASTE
+	if rounds := ch.attackRounds(); rounds != 4 {
+		t.Errorf("expected a hasted character with 18 dexterity to attack 4 times, got %d", rounds)
+	}
+}
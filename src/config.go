@@ -31,6 +31,9 @@ type AppRedisConfiguration struct {
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	Password string `json:"password"`
+
+	/* Keep channel history in Redis so it survives reboots */
+	ChannelHistory bool `json:"channelHistory"`
 }
 
 type AppProfilingConfiguration struct {
@@ -44,7 +47,36 @@ type AppSentryConfiguration struct {
 }
 
 type AppWebConfiguration struct {
-	PublicRoot string `json:"publicRoot"`
+	PublicRoot      string `json:"publicRoot"`
+	StaticDirectory string `json:"staticDirectory"`
+}
+
+type AppTLSConfiguration struct {
+	Enabled         bool   `json:"enabled"`
+	Port            int    `json:"port"`
+	CertificateFile string `json:"certificateFile"`
+	KeyFile         string `json:"keyFile"`
+}
+
+/* argon2id cost; memory is in KiB */
+type AppPasswordConfiguration struct {
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+}
+
+/* Per-connection command token bucket */
+type AppRateLimitConfiguration struct {
+	Burst           int     `json:"burst"`
+	RefillPerSecond float64 `json:"refillPerSecond"`
+	QueueLength     int     `json:"queueLength"`
+}
+
+/* Minutes before an idle session goes AFK or is disconnected, and a linkdead player is extracted; 0 disables */
+type AppIdleConfiguration struct {
+	AfkMinutes        int `json:"afkMinutes"`
+	DisconnectMinutes int `json:"disconnectMinutes"`
+	LinkdeadMinutes   int `json:"linkdeadMinutes"`
 }
 
 type AppConfiguration struct {
@@ -55,6 +87,10 @@ type AppConfiguration struct {
 	SentryConfiguration    AppSentryConfiguration    `json:"sentry"`
 	ProfilingConfiguration AppProfilingConfiguration `json:"profiling"`
 	WebConfiguration       AppWebConfiguration       `json:"web"`
+	TLSConfiguration       AppTLSConfiguration       `json:"tls"`
+	PasswordConfiguration  AppPasswordConfiguration  `json:"password"`
+	RateLimitConfiguration AppRateLimitConfiguration `json:"rateLimit"`
+	IdleConfiguration      AppIdleConfiguration      `json:"idle"`
 
 	greeting []byte
 	motd     []byte
@@ -66,6 +102,24 @@ func init() {
 	/* Defaults */
 	Config = &AppConfiguration{
 		Port: 4000,
+		TLSConfiguration: AppTLSConfiguration{
+			Port: 4443,
+		},
+		IdleConfiguration: AppIdleConfiguration{
+			AfkMinutes:        10,
+			DisconnectMinutes: 30,
+			LinkdeadMinutes:   15,
+		},
+		RateLimitConfiguration: AppRateLimitConfiguration{
+			Burst:           10,
+			RefillPerSecond: 4,
+			QueueLength:     20,
+		},
+		PasswordConfiguration: AppPasswordConfiguration{
+			Memory:      19456,
+			Iterations:  2,
+			Parallelism: 1,
+		},
 		MySQLConfiguration: AppMySQLConfiguration{
 			Host:     "mysql",
 			Port:     3306,
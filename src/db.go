@@ -8,11 +8,17 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
 	"log"
 	"strings"
 )
 
+/* Satisfied by both *sql.DB and *sql.Tx, so that saves can take part in a larger transaction */
+type SQLExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 var TerrainTable map[int]*Terrain
 var Jobs *LinkedList
 var Races *LinkedList
@@ -66,7 +72,10 @@ func (game *Game) LoadRaceTable() {
 			name,
 			display_name,
 			playable,
-			primary_attribute
+			primary_attribute,
+			resist,
+			immune,
+			suscept
 		FROM
 			races
 		WHERE
@@ -82,7 +91,7 @@ func (game *Game) LoadRaceTable() {
 		race := &Race{}
 		var primaryAttribute string = "none"
 
-		err := rows.Scan(&race.Id, &race.Name, &race.DisplayName, &race.Playable, &primaryAttribute)
+		err := rows.Scan(&race.Id, &race.Name, &race.DisplayName, &race.Playable, &primaryAttribute, &race.Resist, &race.Immune, &race.Suscept)
 		if err != nil {
 			log.Printf("Unable to scan race row: %v.\r\n", err)
 			continue
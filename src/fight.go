@@ -20,10 +20,17 @@ type Combat struct {
 }
 
 const (
-	DamageTypeBash   = 0
-	DamageTypeSlash  = 1
-	DamageTypeStab   = 2
-	DamageTypeExotic = 3
+	DamageTypeBash     = 0
+	DamageTypeSlash    = 1
+	DamageTypeStab     = 2
+	DamageTypeExotic   = 3
+	DamageTypeFire     = 4
+	DamageTypeCold     = 5
+	DamageTypeShock    = 6
+	DamageTypePoison   = 7
+	DamageTypeAcid     = 8
+	DamageTypeHoly     = 9
+	DamageTypeNegative = 10
 )
 
 func (ch *Character) GetArmorValues() []int {
@@ -100,45 +107,37 @@ func (game *Game) createCorpse(ch *Character) *ObjectInstance {
 	return obj
 }
 
+/* Damage target, first passing any harm through the target's damage modifiers */
 func (game *Game) Damage(ch *Character, target *Character, display bool, amount int, damageType int) bool {
 	if target == nil {
 		return false
 	}
 
-	var damageTypeVerbTable map[int]string = make(map[int]string)
-	var damageTypeVerbOtherTable map[int]string = make(map[int]string)
-
-	damageTypeVerbOtherTable[DamageTypeBash] = "hits"
-	damageTypeVerbTable[DamageTypeBash] = "hit"
-
-	damageTypeVerbOtherTable[DamageTypeSlash] = "slashes"
-	damageTypeVerbTable[DamageTypeSlash] = "slash"
+	if amount > 0 {
+		amount = target.modifyDamage(damageType, amount)
+	}
 
-	damageTypeVerbOtherTable[DamageTypeStab] = "stabs"
-	damageTypeVerbTable[DamageTypeStab] = "stab"
+	return game.inflictDamage(ch, target, display, amount, damageType)
+}
 
-	damageTypeVerbOtherTable[DamageTypeExotic] = "zaps"
-	damageTypeVerbTable[DamageTypeExotic] = "zap"
+/* Deal an amount of damage which has already been through the modifier pipeline */
+func (game *Game) inflictDamage(ch *Character, target *Character, display bool, amount int, damageType int) bool {
+	if target == nil {
+		return false
+	}
 
 	if display && ch != nil {
-		if ch.Room != nil && target.Room != nil && target.Room == ch.Room {
-			for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
-				character := iter.Value.(*Character)
-				if character != ch && character != target {
-					character.Send(fmt.Sprintf("{G%s{G %s %s{G for %d damage.{x\r\n",
-						ch.GetShortDescriptionUpper(character),
-						damageTypeVerbOtherTable[damageType],
-						target.GetShortDescription(character),
-						amount))
-				}
-			}
-		}
+		game.sendDamageMessages(ch, target, damageNoun(damageType), amount)
+	}
 
-		ch.Send(fmt.Sprintf("{GYou %s %s{G for %d damage.{x\r\n", damageTypeVerbTable[damageType], target.GetShortDescription(ch), amount))
-		target.Send(fmt.Sprintf("{Y%s{Y %s you for %d damage.{x\r\n", ch.GetShortDescriptionUpper(target), damageTypeVerbOtherTable[damageType], amount))
+	/* Being hurt by someone is a rude awakening */
+	if ch != nil && amount > 0 && target.Position < PositionStanding && target.Position > PositionStunned {
+		target.standUp()
+		target.Send("{YYou scramble to your feet!{x\r\n")
 	}
 
 	target.Health -= amount
+	target.markDirty()
 
 	if target.Health > target.MaxHealth {
 		target.Health = target.MaxHealth
@@ -152,28 +151,24 @@ func (game *Game) Damage(ch *Character, target *Character, display bool, amount
 		if target.Room != nil {
 			room := target.Room
 
+			/* Leave the fight while still in the room, so attackers can turn on somebody else */
+			game.stopFighting(target)
+
 			corpse := game.createCorpse(target)
 
 			room.removeCharacter(target)
 			room.AddObject(corpse)
 
-			ch.Game.Objects.Insert(corpse)
+			game.Objects.Insert(corpse)
 
 			blood := game.createBlood(1)
 			room.AddObject(blood)
 
-			ch.Game.Objects.Insert(blood)
-
-			target.Fighting = nil
-			target.Combat = nil
+			game.Objects.Insert(blood)
 
 			for iter := room.Characters.Head; iter != nil; iter = iter.Next {
 				character := iter.Value.(*Character)
 				character.Send(fmt.Sprintf("{R%s{R has been slain!{x\r\n", target.GetShortDescriptionUpper(character)))
-
-				if character.Fighting.IsEqual(target) {
-					character.Fighting = nil
-				}
 			}
 
 			if target.Flags&CHAR_IS_PLAYER != 0 {
@@ -194,6 +189,7 @@ func (game *Game) Damage(ch *Character, target *Character, display bool, amount
 				target.Stamina = 1
 
 				target.Casting = nil
+				target.Position = PositionStanding
 				do_look(target, "")
 			} else {
 				exp := int(target.Experience)
@@ -220,19 +216,174 @@ func (game *Game) Damage(ch *Character, target *Character, display bool, amount
 	return true
 }
 
-func (game *Game) combatUpdate() {
-	game.InvokeNamedEventHandlersWithContextAndArguments("combatUpdate", game.vm.ToValue(game))
-}
-
 func (game *Game) DisposeCombat(combat *Combat) {
 	for _, vch := range combat.Participants {
-		vch.Combat = nil
-		vch.Fighting = nil
+		if vch.Combat == combat {
+			vch.Combat = nil
+			vch.Fighting = nil
+		}
 	}
 
+	combat.Participants = nil
 	game.Fights.Remove(combat)
 }
 
+func (combat *Combat) hasParticipant(ch *Character) bool {
+	for _, participant := range combat.Participants {
+		if participant == ch {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* Whether any other participant still has ch as their target */
+func (combat *Combat) isTargeted(ch *Character) bool {
+	for _, participant := range combat.Participants {
+		if participant != ch && participant.Fighting == ch {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* Add ch to the fight attacking target, bringing them out of any other fight first */
+func (combat *Combat) Join(ch *Character, target *Character) {
+	if ch.Combat != nil && ch.Combat != combat {
+		ch.Combat.Leave(ch)
+	}
+
+	if !combat.hasParticipant(ch) {
+		combat.Participants = append(combat.Participants, ch)
+	}
+
+	ch.Combat = combat
+	ch.Fighting = target
+}
+
+/* Take ch out of the fight; anyone who was attacking them turns on someone else or stops */
+func (combat *Combat) Leave(ch *Character) {
+	participants := make([]*Character, 0, len(combat.Participants))
+
+	for _, participant := range combat.Participants {
+		if participant != ch {
+			participants = append(participants, participant)
+		}
+	}
+
+	combat.Participants = participants
+
+	if ch.Combat == combat {
+		ch.Combat = nil
+		ch.Fighting = nil
+	}
+
+	combat.settle()
+}
+
+/*
+ * Keep every participant pointed at a valid opponent: one still in the fight and in the
+ * same room.  Anyone who has lost theirs turns on whoever is attacking them, and anyone
+ * left with no opponent and no attacker drops out of the fight.
+ */
+func (combat *Combat) settle() {
+	for _, participant := range combat.Participants {
+		target := participant.Fighting
+
+		if target != nil && target.Room != nil && target.Room == participant.Room && combat.hasParticipant(target) {
+			continue
+		}
+
+		participant.Fighting = nil
+
+		for _, other := range combat.Participants {
+			if other != participant && other.Fighting == participant && other.Room != nil && other.Room == participant.Room {
+				participant.Fighting = other
+				break
+			}
+		}
+	}
+
+	participants := make([]*Character, 0, len(combat.Participants))
+
+	for _, participant := range combat.Participants {
+		if participant.Fighting == nil && !combat.isTargeted(participant) {
+			if participant.Combat == combat {
+				participant.Combat = nil
+			}
+
+			continue
+		}
+
+		participants = append(participants, participant)
+	}
+
+	combat.Participants = participants
+}
+
+/* Have ch attack victim, joining the fight victim is already in or starting a new one */
+func (game *Game) StartCombat(ch *Character, victim *Character) *Combat {
+	combat := victim.Combat
+	if combat == nil {
+		combat = ch.Combat
+	}
+
+	if combat == nil {
+		combat = &Combat{StartedAt: time.Now(), Room: ch.Room, Participants: make([]*Character, 0)}
+		game.Fights.Insert(combat)
+	}
+
+	previous := ch.Combat
+	combat.Join(ch, victim)
+
+	if victim.Fighting == nil || victim.Combat != combat {
+		combat.Join(victim, ch)
+	}
+
+	if previous != nil && previous != combat && len(previous.Participants) < 2 {
+		game.DisposeCombat(previous)
+	}
+
+	return combat
+}
+
+/* Take ch out of combat entirely, as on death, flee or quit, ending the fight if it's over */
+func (game *Game) stopFighting(ch *Character) {
+	combat := ch.Combat
+
+	if combat != nil {
+		combat.Leave(ch)
+
+		if len(combat.Participants) < 2 {
+			game.DisposeCombat(combat)
+		}
+	}
+
+	ch.Fighting = nil
+	ch.Combat = nil
+
+	/* Nobody else may go on swinging at a character who is no longer in the fight */
+	over := make([]*Combat, 0)
+
+	for iter := game.Fights.Head; iter != nil; iter = iter.Next {
+		other := iter.Value.(*Combat)
+
+		if other != combat && other.isTargeted(ch) {
+			other.settle()
+
+			if len(other.Participants) < 2 {
+				over = append(over, other)
+			}
+		}
+	}
+
+	for _, other := range over {
+		game.DisposeCombat(other)
+	}
+}
+
 func do_flee(ch *Character, arguments string) {
 	if ch.Room == nil {
 		return
@@ -256,6 +407,11 @@ func do_flee(ch *Character, arguments string) {
 		}
 	}
 
+	if len(exits) == 0 {
+		ch.Send("{RYou look around wildly, but there's nowhere to run!{x\r\n")
+		return
+	}
+
 	if rand.Intn(10) < 7 {
 		ch.Send("{RYou panic and attempt to flee, but can't get away!{x\r\n")
 
@@ -282,19 +438,13 @@ func do_flee(ch *Character, arguments string) {
 		rch := iter.Value.(*Character)
 
 		if rch != ch {
-			/* If they were fighting this player, then this is a good time to stop their participation in the fight and let it dispose */
-			if rch.Fighting == ch {
-				rch.Fighting = nil
-				rch.Combat = nil
-			}
-
 			output := fmt.Sprintf("\r\n{R%s{R has fled %s!{x\r\n", ch.GetShortDescriptionUpper(rch), ExitName[chosenEscape.Direction])
 			rch.Send(output)
 		}
 	}
 
-	ch.Fighting = nil
-	ch.Combat = nil
+	/* Those who were fighting this character turn on somebody else, or the fight ends */
+	ch.Game.stopFighting(ch)
 
 	ch.Room.removeCharacter(ch)
 
@@ -327,11 +477,6 @@ func do_kill(ch *Character, arguments string) {
 		return
 	}
 
-	if ch.Fighting != nil {
-		ch.Send("You are already fighting somebody else!\r\n")
-		return
-	}
-
 	if len(arguments) < 1 {
 		ch.Send("Attack who?\r\n")
 		return
@@ -344,19 +489,27 @@ func do_kill(ch *Character, arguments string) {
 		return
 	}
 
-	combat := &Combat{}
-	combat.StartedAt = time.Now()
-	combat.Room = ch.Room
-	combat.Participants = []*Character{ch, target}
-	ch.Game.Fights.Insert(combat)
+	if ch.Fighting != nil {
+		if target == ch.Fighting {
+			ch.Send("You're already fighting them!\r\n")
+			return
+		}
 
-	ch.Fighting = target
+		/* Mid-fight, attention can only turn to somebody else in the same fight */
+		if target.Combat == nil || target.Combat != ch.Combat {
+			ch.Send("You are already fighting somebody else!\r\n")
+			return
+		}
 
-	if target.Fighting == nil {
-		target.Fighting = ch
-		target.Combat = combat
+		ch.Fighting = target
+
+		ch.Send(fmt.Sprintf("\r\n{GYou turn to attack %s{G!{x\r\n", target.GetShortDescription(ch)))
+		target.Send(fmt.Sprintf("\r\n{G%s{G turns to attack you!{x\r\n", ch.GetShortDescriptionUpper(target)))
+		return
 	}
 
+	ch.Game.StartCombat(ch, target)
+
 	ch.Send(fmt.Sprintf("\r\n{GYou begin attacking %s{G!{x\r\n", target.GetShortDescription(ch)))
 	target.Send(fmt.Sprintf("\r\n{G%s{G begins attacking you!{x\r\n", ch.GetShortDescriptionUpper(target)))
 
@@ -371,3 +524,175 @@ func do_kill(ch *Character, arguments string) {
 		}
 	}
 }
+
+/* Percent chance of drawing attackers away from somebody else */
+func rescueChance(rescuerLevel int, attackerLevel int) int {
+	chance := 50 + 2*(rescuerLevel-attackerLevel)
+
+	if chance < 10 {
+		return 10
+	}
+
+	if chance > 90 {
+		return 90
+	}
+
+	return chance
+}
+
+/* Everyone in this character's fight attacking them from the same room */
+func (ch *Character) attackers() []*Character {
+	attackers := make([]*Character, 0)
+
+	if ch.Combat == nil {
+		return attackers
+	}
+
+	for _, participant := range ch.Combat.Participants {
+		if participant.Fighting == ch && participant.Room != nil && participant.Room == ch.Room {
+			attackers = append(attackers, participant)
+		}
+	}
+
+	return attackers
+}
+
+func do_rescue(ch *Character, arguments string) {
+	if ch.Room == nil {
+		return
+	}
+
+	if ch.Room.Flags&ROOM_SAFE != 0 {
+		ch.Send("{WYou cannot do that here.{x\r\n")
+		return
+	}
+
+	if len(arguments) < 1 {
+		ch.Send("Rescue whom?\r\n")
+		return
+	}
+
+	victim := ch.FindCharacterInRoom(arguments)
+	if victim == nil {
+		ch.Send("They aren't here.\r\n")
+		return
+	}
+
+	if victim == ch {
+		ch.Send("What about fleeing instead?\r\n")
+		return
+	}
+
+	if ch.Fighting == victim {
+		ch.Send("Too late.\r\n")
+		return
+	}
+
+	attackers := victim.attackers()
+	if len(attackers) == 0 {
+		ch.Send(fmt.Sprintf("Nobody is attacking %s{x.\r\n", victim.GetShortDescription(ch)))
+		return
+	}
+
+	ch.SetWait(2 * PulsesPerSecond)
+
+	if rand.Intn(100) >= rescueChance(int(ch.Level), int(attackers[0].Level)) {
+		ch.Send("You fail the rescue.\r\n")
+		return
+	}
+
+	ch.Send(fmt.Sprintf("{WYou rescue %s{W!{x\r\n", victim.GetShortDescription(ch)))
+	victim.Send(fmt.Sprintf("{W%s{W rescues you!{x\r\n", ch.GetShortDescriptionUpper(victim)))
+
+	for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch != ch && rch != victim {
+			rch.Send(fmt.Sprintf("{W%s{W rescues %s{W!{x\r\n", ch.GetShortDescriptionUpper(rch), victim.GetShortDescription(rch)))
+		}
+	}
+
+	combat := ch.Game.StartCombat(ch, attackers[0])
+
+	for _, attacker := range attackers {
+		attacker.Fighting = ch
+	}
+
+	/* With nobody left attacking them, the rescued character drops out of the fight */
+	if victim.Combat == combat {
+		combat.Leave(victim)
+	}
+}
+
+func do_assist(ch *Character, arguments string) {
+	var ally *Character = nil
+
+	if ch.Room == nil {
+		return
+	}
+
+	if ch.Fighting != nil {
+		ch.Send("You're already fighting!\r\n")
+		return
+	}
+
+	if ch.Room.Flags&ROOM_SAFE != 0 {
+		ch.Send("{WYou cannot do that here.{x\r\n")
+		return
+	}
+
+	if len(arguments) < 1 {
+		/* With nobody named, help out whichever member of the group here is fighting */
+		if ch.Group != nil {
+			for iter := ch.Group.Head; iter != nil; iter = iter.Next {
+				gch := iter.Value.(*Character)
+
+				if gch != ch && gch.Room == ch.Room && gch.Fighting != nil {
+					ally = gch
+					break
+				}
+			}
+		}
+
+		if ally == nil {
+			ch.Send("Assist whom?\r\n")
+			return
+		}
+	} else {
+		ally = ch.FindCharacterInRoom(arguments)
+		if ally == nil {
+			ch.Send("They aren't here.\r\n")
+			return
+		}
+
+		if ally == ch {
+			ch.Send("You can't assist yourself.\r\n")
+			return
+		}
+	}
+
+	enemy := ally.Fighting
+	if enemy == nil || enemy.Room != ch.Room {
+		ch.Send(fmt.Sprintf("%s{x isn't fighting anybody.\r\n", ally.GetShortDescriptionUpper(ch)))
+		return
+	}
+
+	if enemy == ch {
+		ch.Send("You can't assist somebody against yourself!\r\n")
+		return
+	}
+
+	ch.Game.StartCombat(ch, enemy)
+
+	ch.Send(fmt.Sprintf("{GYou join %s{G in fighting %s{G!{x\r\n", ally.GetShortDescription(ch), enemy.GetShortDescription(ch)))
+	ally.Send(fmt.Sprintf("{G%s{G joins you in fighting %s{G!{x\r\n", ch.GetShortDescriptionUpper(ally), enemy.GetShortDescription(ally)))
+	enemy.Send(fmt.Sprintf("{G%s{G joins the fight against you!{x\r\n", ch.GetShortDescriptionUpper(enemy)))
+
+	for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch != ch && rch != ally && rch != enemy {
+			rch.Send(fmt.Sprintf("{G%s{G joins %s{G in fighting %s{G!{x\r\n", ch.GetShortDescriptionUpper(rch), ally.GetShortDescription(rch), enemy.GetShortDescription(rch)))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+func newTestFighters(count int) (*Game, []*Character) {
+	game := &Game{Fights: NewLinkedList()}
+	room := &Room{Characters: NewLinkedList()}
+	fighters := make([]*Character, count)
+
+	for index := range fighters {
+		fighters[index] = NewCharacter()
+		fighters[index].Room = room
+	}
+
+	return game, fighters
+}
+
+func TestStartCombatJoinsExistingFight(t *testing.T) {
+	game, fighters := newTestFighters(3)
+	a, b, c := fighters[0], fighters[1], fighters[2]
+
+	game.StartCombat(a, b)
+	game.StartCombat(c, b)
+
+	if game.Fights.Count != 1 {
+		t.Fatalf("expected a single fight, got %d", game.Fights.Count)
+	}
+
+	if len(b.Combat.Participants) != 3 {
+		t.Errorf("expected 3 participants, got %d", len(b.Combat.Participants))
+	}
+
+	if b.Fighting != a {
+		t.Errorf("expected the victim to keep fighting their first attacker")
+	}
+}
+
+func TestStopFightingEndsFightWithNobodyLeft(t *testing.T) {
+	game, fighters := newTestFighters(3)
+	a, b, c := fighters[0], fighters[1], fighters[2]
+
+	game.StartCombat(a, b)
+	game.StartCombat(c, b)
+	game.stopFighting(b)
+
+	if game.Fights.Count != 0 {
+		t.Errorf("expected the fight to be disposed, %d remain", game.Fights.Count)
+	}
+
+	for _, ch := range fighters {
+		if ch.Fighting != nil || ch.Combat != nil {
+			t.Errorf("expected every fighter to be out of combat")
+		}
+	}
+}
+
+func TestStopFightingTurnsAttackersOnSomebodyElse(t *testing.T) {
+	game, fighters := newTestFighters(3)
+	a, b, d := fighters[0], fighters[1], fighters[2]
+
+	game.StartCombat(a, b)
+	game.StartCombat(d, a)
+	game.stopFighting(b)
+
+	if a.Fighting != d {
+		t.Errorf("expected the attacker to turn on the one attacking them")
+	}
+
+	if a.Combat == nil || len(a.Combat.Participants) != 2 || game.Fights.Count != 1 {
+		t.Errorf("expected the fight to go on between the two left")
+	}
+}
+
+func TestStopFightingIgnoresFightersInOtherRooms(t *testing.T) {
+	game, fighters := newTestFighters(3)
+	a, b, d := fighters[0], fighters[1], fighters[2]
+
+	game.StartCombat(a, b)
+	game.StartCombat(d, a)
+
+	d.Room = &Room{Characters: NewLinkedList()}
+	game.stopFighting(b)
+
+	if game.Fights.Count != 0 {
+		t.Errorf("expected the fight to end with its remaining fighters apart")
+	}
+}
+
+func TestRescueChance(t *testing.T) {
+	if chance := rescueChance(10, 10); chance != 50 {
+		t.Errorf("expected an even chance between equals, got %d", chance)
+	}
+
+	if chance := rescueChance(50, 1); chance != 90 {
+		t.Errorf("expected a rescue chance capped at 90%%, got %d", chance)
+	}
+
+	if chance := rescueChance(1, 50); chance != 10 {
+		t.Errorf("expected a rescue chance floored at 10%%, got %d", chance)
+	}
+}
@@ -11,8 +11,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/dop251/goja"
@@ -39,7 +39,12 @@ type Game struct {
 	Zones        *LinkedList `json:"zones"`
 	ScriptTimers *LinkedList `json:"scriptTimers"`
 
+	listener    net.Listener
 	clients     map[*Client]bool
+	bans        *LinkedList
+	channels    map[string]*Channel
+	socials     map[string]*Social
+	logins      *LoginThrottle
 	skills      map[uint]*Skill
 	world       map[uint]*Room
 	shops       map[uint]*Shop
@@ -78,7 +83,7 @@ func NewGame() (*Game, error) {
 	game.register = make(chan *Client)
 	game.unregister = make(chan *Client)
 	game.quitRequest = make(chan *Client)
-	game.shutdownRequest = make(chan bool)
+	game.shutdownRequest = make(chan bool, 1)
 	game.webhookMessage = make(chan string)
 	game.clientMessage = make(chan ClientTextMessage)
 	game.planeGenerationCompleted = make(chan int)
@@ -88,6 +93,7 @@ func NewGame() (*Game, error) {
 	game.Objects = NewLinkedList()
 	game.ScriptTimers = NewLinkedList()
 	game.Planes = NewLinkedList()
+	game.logins = NewLoginThrottle()
 
 	/* Initialize services we'll inject elsewhere through the game instance. */
 	game.db, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?multiStatements=true&parseTime=true",
@@ -180,6 +186,21 @@ func NewGame() (*Game, error) {
 		return nil, err
 	}
 
+	err = game.LoadBans()
+	if err != nil {
+		return nil, err
+	}
+
+	err = game.LoadChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	err = game.LoadSocials()
+	if err != nil {
+		return nil, err
+	}
+
 	err = game.InitScripting()
 	if err != nil {
 		return nil, err
@@ -254,6 +275,15 @@ func (game *Game) Run() {
 	processZoneUpdateTicker := time.NewTicker(1 * time.Minute)
 	game.ZoneUpdate()
 
+	/* Idle sessions and linkdead players */
+	processIdleTicker := time.NewTicker(30 * time.Second)
+
+	/* Periodically persist players and planes with unsaved changes */
+	processAutosaveTicker := time.NewTicker(AutosaveInterval)
+
+	/* Count down wait states and run input held behind them */
+	processWaitTicker := time.NewTicker(PulseDuration)
+
 	for {
 		select {
 		case <-processUpdateTicker.C:
@@ -262,6 +292,15 @@ func (game *Game) Run() {
 		case <-processZoneUpdateTicker.C:
 			game.ZoneUpdate()
 
+		case <-processIdleTicker.C:
+			game.idleUpdate()
+
+		case <-processAutosaveTicker.C:
+			game.autosave()
+
+		case <-processWaitTicker.C:
+			game.waitUpdate()
+
 		case <-processObjectUpdateTicker.C:
 			game.objectUpdate()
 
@@ -313,10 +352,16 @@ func (game *Game) Run() {
 			log.Print(out)
 			game.broadcast(out, WiznetBroadcastFilter)
 
+			if ban := game.FindHostBan(remoteHost(client.conn.RemoteAddr())); ban != nil {
+				game.reportBanHit(ban, client, "a connection")
+				client.refuse("Your site has been banned from this game.\r\n")
+				break
+			}
+
 			client.ConnectionState = ConnectionStateName
 
 			client.Send(Config.greeting)
-			client.Send([]byte("By what name do you wish to be known? "))
+			client.Send([]byte("Account name: "))
 
 		case client := <-game.unregister:
 			delete(game.clients, client)
@@ -326,7 +371,12 @@ func (game *Game) Run() {
 			if client.Character != nil {
 				logOutput = fmt.Sprintf("Lost connection with %s@%s.\r\n", client.Character.Name, client.conn.RemoteAddr().String())
 
-				client.Character.Client = nil
+				/* A reconnect may already have handed the character to a newer connection */
+				if client.Character.Client == client {
+					client.Character.Client = nil
+					client.Character.linkdeadAt = time.Now()
+				}
+
 				log.Print(logOutput)
 				game.broadcast(logOutput, WiznetBroadcastFilter)
 				break
@@ -353,7 +403,7 @@ func (game *Game) Run() {
 			}
 
 		case <-game.shutdownRequest:
-			os.Exit(0)
+			game.shutdown()
 			return
 		}
 	}
@@ -0,0 +1,251 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+/*
+ * Generic MUD Communication Protocol, carried over telnet option 201 as
+ * IAC SB GMCP <Package.Subpackage> <JSON data> IAC SE.
+ *
+ * Reference: https://www.gammon.com.au/gmcp
+ */
+
+type GMCPCharVitals struct {
+	Health     int `json:"health"`
+	MaxHealth  int `json:"maxHealth"`
+	Mana       int `json:"mana"`
+	MaxMana    int `json:"maxMana"`
+	Stamina    int `json:"stamina"`
+	MaxStamina int `json:"maxStamina"`
+}
+
+type GMCPCharStatus struct {
+	Name       string `json:"name"`
+	Race       string `json:"race"`
+	Job        string `json:"job"`
+	Level      uint   `json:"level"`
+	Experience uint   `json:"experience"`
+	Gold       int    `json:"gold"`
+}
+
+type GMCPRoomInfo struct {
+	Id    uint            `json:"num"`
+	Name  string          `json:"name"`
+	Area  string          `json:"area"`
+	Exits map[string]uint `json:"exits"`
+	X     int             `json:"x"`
+	Y     int             `json:"y"`
+	Z     int             `json:"z"`
+}
+
+type GMCPCommChannelText struct {
+	Channel string `json:"channel"`
+	Talker  string `json:"talker"`
+	Text    string `json:"text"`
+}
+
+/*
+ * What was last published to a client, so that unchanged values aren't resent every
+ * prompt.  The supports set is written from the read pump, so is guarded by telnetMutex.
+ */
+type GMCPState struct {
+	supports map[string]bool
+	vitals   GMCPCharVitals
+	status   GMCPCharStatus
+	room     *Room
+}
+
+func init() {
+	TelnetOptionTable[TelnetGMCP] = &TelnetOption{
+		Name:  "GMCP",
+		Local: true,
+		OnSubnegotiation: func(client *Client, data []byte) {
+			client.handleGMCP(data)
+		},
+	}
+}
+
+/* Handle a message from the client; only the Core package is meaningful inbound. */
+func (client *Client) handleGMCP(data []byte) {
+	var pkg string = string(data)
+	var payload []byte = nil
+
+	if index := bytes.IndexByte(data, ' '); index >= 0 {
+		pkg = string(data[:index])
+		payload = data[index+1:]
+	}
+
+	switch strings.ToLower(pkg) {
+	case "core.hello":
+		/* Client name and version: nothing to do with it yet */
+
+	case "core.ping":
+		client.sendGMCP("Core.Ping", nil)
+
+	case "core.supports.set", "core.supports.add", "core.supports.remove":
+		var modules []string
+
+		err := json.Unmarshal(payload, &modules)
+		if err != nil {
+			log.Printf("Ignoring malformed GMCP %s: %v\r\n", pkg, err)
+			return
+		}
+
+		client.telnetMutex.Lock()
+		defer client.telnetMutex.Unlock()
+
+		if strings.ToLower(pkg) == "core.supports.set" || client.gmcp.supports == nil {
+			client.gmcp.supports = make(map[string]bool)
+		}
+
+		for _, module := range modules {
+			/* Entries take the form "Module version"; skip any with no module at all */
+			fields := strings.Fields(module)
+			if len(fields) == 0 {
+				continue
+			}
+
+			client.gmcp.supports[strings.ToLower(fields[0])] = strings.ToLower(pkg) != "core.supports.remove"
+		}
+	}
+}
+
+/* Clients which never sent Core.Supports get everything. */
+func (client *Client) gmcpSupports(pkg string) bool {
+	module := strings.ToLower(strings.SplitN(pkg, ".", 2)[0])
+	if module == "core" {
+		return true
+	}
+
+	client.telnetMutex.Lock()
+	defer client.telnetMutex.Unlock()
+
+	if client.gmcp.supports == nil {
+		return true
+	}
+
+	return client.gmcp.supports[module]
+}
+
+/* Send a GMCP message, reporting whether it went out: it won't without GMCP or support for its module */
+func (client *Client) sendGMCP(pkg string, data interface{}) (bool, error) {
+	if !client.isLocalOptionEnabled(TelnetGMCP) || !client.gmcpSupports(pkg) {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(pkg)
+
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return false, err
+		}
+
+		buf.WriteByte(' ')
+		buf.Write(encoded)
+	}
+
+	client.sendSubnegotiation(TelnetGMCP, buf.Bytes())
+	return true, nil
+}
+
+func (ch *Character) sendGMCP(pkg string, data interface{}) {
+	if ch.Client == nil {
+		return
+	}
+
+	_, err := ch.Client.sendGMCP(pkg, data)
+	if err != nil {
+		log.Printf("Failed to send GMCP %s to %s: %v\r\n", pkg, ch.Name, err)
+	}
+}
+
+func (ch *Character) sendGMCPChannel(channel string, talker *Character, text string) {
+	ch.sendGMCP("Comm.Channel.Text", GMCPCommChannelText{
+		Channel: channel,
+		Talker:  talker.Name,
+		Text:    strings.TrimSpace(StripColourCodes(text)),
+	})
+}
+
+func (room *Room) gmcpInfo() GMCPRoomInfo {
+	info := GMCPRoomInfo{
+		Id:    room.Id,
+		Name:  room.Name,
+		Exits: make(map[string]uint),
+		X:     room.X,
+		Y:     room.Y,
+		Z:     room.Z,
+	}
+
+	if room.Zone != nil {
+		info.Area = room.Zone.Name
+	}
+
+	for direction, exit := range room.Exit {
+		if exit.To != nil {
+			info.Exits[ExitName[direction]] = exit.To.Id
+		}
+	}
+
+	return info
+}
+
+/* Publish whichever of the character's vitals, status and location changed since last time */
+func (client *Client) updateGMCP() {
+	ch := client.Character
+	if ch == nil || client.ConnectionState != ConnectionStatePlaying || !client.isLocalOptionEnabled(TelnetGMCP) {
+		return
+	}
+
+	vitals := GMCPCharVitals{
+		Health:     ch.Health,
+		MaxHealth:  ch.MaxHealth,
+		Mana:       ch.Mana,
+		MaxMana:    ch.MaxMana,
+		Stamina:    ch.Stamina,
+		MaxStamina: ch.MaxStamina,
+	}
+
+	if vitals != client.gmcp.vitals {
+		client.gmcp.vitals = vitals
+		ch.sendGMCP("Char.Vitals", vitals)
+	}
+
+	status := GMCPCharStatus{
+		Name:       ch.Name,
+		Level:      ch.Level,
+		Experience: ch.Experience,
+		Gold:       ch.Gold,
+	}
+
+	if ch.Race != nil {
+		status.Race = strings.TrimSpace(ch.Race.DisplayName)
+	}
+
+	if ch.Job != nil {
+		status.Job = strings.TrimSpace(ch.Job.DisplayName)
+	}
+
+	if status != client.gmcp.status {
+		client.gmcp.status = status
+		ch.sendGMCP("Char.Status", status)
+	}
+
+	if ch.Room != nil && ch.Room != client.gmcp.room {
+		client.gmcp.room = ch.Room
+		ch.sendGMCP("Room.Info", ch.Room.gmcpInfo())
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const IdleAwayMessage = "idle"
+
+/* A player whose connection dropped but who is still standing in the world */
+func (ch *Character) isLinkdead() bool {
+	return ch.Flags&CHAR_IS_PLAYER != 0 && ch.Client == nil
+}
+
+/* Any input at all brings a player back from an AFK the idle timer set */
+func (client *Client) noteActivity() {
+	client.lastInputAt = time.Now()
+
+	ch := client.Character
+	if ch != nil && client.ConnectionState == ConnectionStatePlaying && ch.Afk != nil && ch.Afk.automatic {
+		ch.Afk = nil
+		ch.Send("{GYou are no longer idle.{x\r\n")
+		ch.replayQueuedTells()
+	}
+}
+
+/* Mark idle sessions AFK, disconnect the ones idle too long, extract stale linkdead players and forget old login failures */
+func (game *Game) idleUpdate() {
+	now := time.Now()
+
+	afkAfter := time.Duration(Config.IdleConfiguration.AfkMinutes) * time.Minute
+	disconnectAfter := time.Duration(Config.IdleConfiguration.DisconnectMinutes) * time.Minute
+	linkdeadAfter := time.Duration(Config.IdleConfiguration.LinkdeadMinutes) * time.Minute
+
+	game.logins.Sweep(now)
+
+	for client := range game.clients {
+		ch := client.Character
+		if ch == nil || client.ConnectionState != ConnectionStatePlaying {
+			continue
+		}
+
+		idle := now.Sub(client.lastInputAt)
+
+		if disconnectAfter > 0 && idle >= disconnectAfter {
+			out := fmt.Sprintf("%s disconnected after idling for %d minutes.\r\n", ch.Name, int(idle.Minutes()))
+			log.Print(out)
+			game.broadcast(out, WiznetBroadcastFilter)
+
+			ch.Send("{WYou have been idle too long and are being disconnected.{x\r\n")
+			do_quit(ch, "")
+			continue
+		}
+
+		if afkAfter > 0 && idle >= afkAfter && ch.Afk == nil {
+			ch.Afk = &AwayFromKeyboard{
+				startedAt: client.lastInputAt,
+				message:   IdleAwayMessage,
+				automatic: true,
+			}
+
+			ch.Send("{GYou have been idle for a while and are now AFK.{x\r\n")
+		}
+	}
+
+	if linkdeadAfter <= 0 {
+		return
+	}
+
+	expired := make([]*Character, 0)
+
+	for iter := game.Characters.Head; iter != nil; iter = iter.Next {
+		ch := iter.Value.(*Character)
+
+		if ch.isLinkdead() && !ch.linkdeadAt.IsZero() && now.Sub(ch.linkdeadAt) >= linkdeadAfter {
+			expired = append(expired, ch)
+		}
+	}
+
+	for _, ch := range expired {
+		out := fmt.Sprintf("%s was extracted after being linkdead for %d minutes.\r\n", ch.Name, int(now.Sub(ch.linkdeadAt).Minutes()))
+		log.Print(out)
+		game.broadcast(out, WiznetBroadcastFilter)
+
+		if !ch.Save() {
+			log.Printf("Failed to save linkdead player %s.\r\n", ch.Name)
+		}
+
+		ch.leaveWorld(fmt.Sprintf("{W%s fades from the world.{x\r\n", ch.Name))
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+const MaxIgnoredPlayers = 50
+
+/* Whether this character has chosen to ignore another; only players can be ignored */
+func (ch *Character) IsIgnoring(other *Character) bool {
+	if other == nil || other == ch || other.Flags&CHAR_IS_PLAYER == 0 {
+		return false
+	}
+
+	_, ok := ch.ignoring[other.Id]
+	return ok
+}
+
+/* The same check by name, for things like channel history which outlive the talker */
+func (ch *Character) isIgnoringName(name string) bool {
+	for _, ignored := range ch.ignoring {
+		if strings.EqualFold(ignored, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* A broadcast filter for messages from talker, passing everyone not ignoring them */
+func IgnoreBroadcastFilter(talker *Character) func(ch *Character) bool {
+	return func(ch *Character) bool {
+		return !ch.IsIgnoring(talker)
+	}
+}
+
+func (ch *Character) LoadPlayerIgnores() error {
+	rows, err := ch.Game.db.Query(`
+		SELECT
+			pc_ignore.ignored_id,
+			player_characters.username
+		FROM
+			pc_ignore
+		INNER JOIN
+			player_characters ON player_characters.id = pc_ignore.ignored_id
+		WHERE
+			pc_ignore.player_character_id = ?
+		AND
+			player_characters.deleted_at IS NULL
+	`, ch.Id)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+
+		ch.ignoring[id] = name
+	}
+
+	return nil
+}
+
+func (ch *Character) ignore(id int, name string) error {
+	_, err := ch.Game.db.Exec(`
+		INSERT INTO
+			pc_ignore(player_character_id, ignored_id)
+		VALUES
+			(?, ?)
+	`, ch.Id, id)
+	if err != nil {
+		return err
+	}
+
+	ch.ignoring[id] = name
+	return nil
+}
+
+func (ch *Character) unignore(id int) error {
+	_, err := ch.Game.db.Exec(`
+		DELETE FROM
+			pc_ignore
+		WHERE
+			player_character_id = ?
+		AND
+			ignored_id = ?
+	`, ch.Id, id)
+	if err != nil {
+		return err
+	}
+
+	delete(ch.ignoring, id)
+	return nil
+}
+
+func do_ignore(ch *Character, arguments string) {
+	if ch.Flags&CHAR_IS_PLAYER == 0 {
+		return
+	}
+
+	name, _ := OneArgument(arguments)
+	name = strings.Title(strings.ToLower(name))
+
+	if name == "" {
+		if len(ch.ignoring) == 0 {
+			ch.Send("You aren't ignoring anybody.\r\n")
+			return
+		}
+
+		names := make([]string, 0, len(ch.ignoring))
+		for _, ignored := range ch.ignoring {
+			names = append(names, ignored)
+		}
+
+		sort.Strings(names)
+
+		ch.Send(fmt.Sprintf("You are ignoring: %s\r\n", strings.Join(names, ", ")))
+		return
+	}
+
+	for id, ignored := range ch.ignoring {
+		if ignored == name {
+			err := ch.unignore(id)
+			if err != nil {
+				log.Printf("Failed to unignore %s for %s: %v.\r\n", name, ch.Name, err)
+				ch.Send("A strange force prevents you from doing that.\r\n")
+				return
+			}
+
+			ch.Send(fmt.Sprintf("You stop ignoring %s.\r\n", name))
+			return
+		}
+	}
+
+	if name == ch.Name {
+		ch.Send("You can't ignore yourself.\r\n")
+		return
+	}
+
+	if len(ch.ignoring) >= MaxIgnoredPlayers {
+		ch.Send(fmt.Sprintf("You can't ignore more than %d players.\r\n", MaxIgnoredPlayers))
+		return
+	}
+
+	var id int
+	var level uint
+
+	row := ch.Game.db.QueryRow(`
+		SELECT
+			id,
+			level
+		FROM
+			player_characters
+		WHERE
+			username = ?
+		AND
+			deleted_at IS NULL
+	`, name)
+
+	err := row.Scan(&id, &level)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to look up %s to ignore: %v.\r\n", name, err)
+		}
+
+		ch.Send("No player by that name exists.\r\n")
+		return
+	}
+
+	if level >= LevelAdmin {
+		ch.Send("You can't ignore the gods.\r\n")
+		return
+	}
+
+	err = ch.ignore(id, name)
+	if err != nil {
+		log.Printf("Failed to ignore %s for %s: %v.\r\n", name, ch.Name, err)
+		ch.Send("A strange force prevents you from doing that.\r\n")
+		return
+	}
+
+	ch.Send(fmt.Sprintf("You are now ignoring %s.\r\n", name))
+}
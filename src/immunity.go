@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"strings"
+)
+
+/* How a character stands against damage of a given type */
+const (
+	ImmunityNormal      = 0
+	ImmunityResistant   = 1
+	ImmunitySusceptible = 2
+	ImmunityImmune      = 3
+)
+
+/* Damage type names as used in resistance masks; the same bits serve RESIST_, IMMUNE_ and SUSCEPT_ */
+var DamageTypeFlagTable []Flag = []Flag{
+	{Name: "bash", Flag: RESIST_BASH},
+	{Name: "slash", Flag: RESIST_SLASH},
+	{Name: "stab", Flag: RESIST_STAB},
+	{Name: "exotic", Flag: RESIST_EXOTIC},
+	{Name: "fire", Flag: RESIST_FIRE},
+	{Name: "cold", Flag: RESIST_COLD},
+	{Name: "shock", Flag: RESIST_SHOCK},
+	{Name: "poison", Flag: RESIST_POISON},
+	{Name: "acid", Flag: RESIST_ACID},
+	{Name: "holy", Flag: RESIST_HOLY},
+	{Name: "negative", Flag: RESIST_NEGATIVE},
+}
+
+func FindDamageTypeFlag(flag string) *Flag {
+	for _, f := range DamageTypeFlagTable {
+		if strings.EqualFold(f.Name, flag) {
+			return &f
+		}
+	}
+
+	return nil
+}
+
+/* Name every damage type set in a mask, or "none" */
+func damageTypeMaskNames(mask int) string {
+	names := make([]string, 0)
+
+	for _, f := range DamageTypeFlagTable {
+		if mask&f.Flag != 0 {
+			names = append(names, f.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, " ")
+}
+
+/*
+ * Settle a damage type against a set of masks as ROM's check_immune does: immunity wins
+ * outright, while being both resistant and susceptible to something cancels out.
+ */
+func immunityLevel(damageType int, resist int, immune int, suscept int) int {
+	if damageType < 0 || damageType >= len(DamageTypeFlagTable) {
+		return ImmunityNormal
+	}
+
+	bit := 1 << uint(damageType)
+
+	switch {
+	case immune&bit != 0:
+		return ImmunityImmune
+
+	case resist&bit != 0 && suscept&bit != 0:
+		return ImmunityNormal
+
+	case resist&bit != 0:
+		return ImmunityResistant
+
+	case suscept&bit != 0:
+		return ImmunitySusceptible
+	}
+
+	return ImmunityNormal
+}
+
+/* A character's own masks combined with their race's and any immunity granted by effects */
+func (ch *Character) resistanceMasks() (int, int, int) {
+	resist, immune, suscept := ch.Resist, ch.Immune, ch.Suscept
+
+	if ch.Race != nil {
+		resist |= ch.Race.Resist
+		immune |= ch.Race.Immune
+		suscept |= ch.Race.Suscept
+	}
+
+	if ch.Effects != nil {
+		for iter := ch.Effects.Head; iter != nil; iter = iter.Next {
+			fx := iter.Value.(*Effect)
+
+			if fx.EffectType == EffectTypeImmunity {
+				immune |= fx.Bits
+			}
+		}
+	}
+
+	return resist, immune, suscept
+}
+
+/* One of the Immunity constants, for how ch would take damage of a type */
+func (ch *Character) CheckImmune(damageType int) int {
+	resist, immune, suscept := ch.resistanceMasks()
+
+	return immunityLevel(damageType, resist, immune, suscept)
+}
+
+/* One step of the damage pipeline, returning what remains of an amount of damage about to be dealt to ch */
+type DamageModifier func(ch *Character, damageType int, amount int) int
+
+/* Every modifier harm passes through, in order, before it is dealt */
+var DamageModifierPipeline []DamageModifier = []DamageModifier{
+	sanctuaryDamageModifier,
+	immunityDamageModifier,
+}
+
+func sanctuaryDamageModifier(ch *Character, damageType int, amount int) int {
+	if ch.Affected&AFFECT_SANCTUARY != 0 {
+		return amount / 2
+	}
+
+	return amount
+}
+
+func immunityDamageModifier(ch *Character, damageType int, amount int) int {
+	switch ch.CheckImmune(damageType) {
+	case ImmunityImmune:
+		return 0
+
+	case ImmunityResistant:
+		return amount - amount/3
+
+	case ImmunitySusceptible:
+		return amount + amount/2
+	}
+
+	return amount
+}
+
+/* Pass an amount of damage of some type through the pipeline, never leaving it negative */
+func (ch *Character) modifyDamage(damageType int, amount int) int {
+	for _, modifier := range DamageModifierPipeline {
+		if amount <= 0 {
+			return 0
+		}
+
+		amount = modifier(ch, damageType, amount)
+	}
+
+	if amount < 0 {
+		return 0
+	}
+
+	return amount
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+func TestImmunityLevel(t *testing.T) {
+	cases := []struct {
+		resist   int
+		immune   int
+		suscept  int
+		expected int
+	}{
+		{0, 0, 0, ImmunityNormal},
+		{RESIST_FIRE, 0, 0, ImmunityResistant},
+		{0, 0, SUSCEPT_FIRE, ImmunitySusceptible},
+		{RESIST_FIRE, 0, SUSCEPT_FIRE, ImmunityNormal},
+		{RESIST_FIRE, IMMUNE_FIRE, SUSCEPT_FIRE, ImmunityImmune},
+		{RESIST_COLD, IMMUNE_SHOCK, SUSCEPT_POISON, ImmunityNormal},
+	}
+
+	for _, c := range cases {
+		if level := immunityLevel(DamageTypeFire, c.resist, c.immune, c.suscept); level != c.expected {
+			t.Errorf("expected immunity level %d for masks %d/%d/%d, got %d", c.expected, c.resist, c.immune, c.suscept, level)
+		}
+	}
+}
+
+func TestDamageTypeFlagTableMatchesDamageTypes(t *testing.T) {
+	for damageType, f := range DamageTypeFlagTable {
+		if f.Flag != 1<<uint(damageType) {
+			t.Errorf("expected %s to be bit %d", f.Name, damageType)
+		}
+	}
+
+	if flag := FindDamageTypeFlag("FIRE"); flag == nil || flag.Flag != RESIST_FIRE {
+		t.Errorf("expected to find the fire damage type by name")
+	}
+}
+
+func TestResistanceMasksCombineRaceAndEffects(t *testing.T) {
+	ch := NewCharacter()
+	ch.Race = &Race{Resist: RESIST_POISON}
+	ch.Suscept = SUSCEPT_COLD
+
+	if level := ch.CheckImmune(DamageTypePoison); level != ImmunityResistant {
+		t.Errorf("expected a racial resistance to poison, got %d", level)
+	}
+
+	if level := ch.CheckImmune(DamageTypeCold); level != ImmunitySusceptible {
+		t.Errorf("expected a susceptibility to cold, got %d", level)
+	}
+
+	ch.AddEffect(&Effect{EffectType: EffectTypeImmunity, Bits: IMMUNE_COLD})
+
+	if level := ch.CheckImmune(DamageTypeCold); level != ImmunityImmune {
+		t.Errorf("expected an effect to grant immunity to cold, got %d", level)
+	}
+}
+
+func TestModifyDamage(t *testing.T) {
+	ch := NewCharacter()
+	ch.Resist = RESIST_FIRE
+	ch.Suscept = SUSCEPT_HOLY
+	ch.Immune = IMMUNE_POISON
+
+	cases := []struct {
+		damageType int
+		amount     int
+		expected   int
+	}{
+		{DamageTypeBash, 30, 30},
+		{DamageTypeFire, 30, 20},
+		{DamageTypeHoly, 30, 45},
+		{DamageTypePoison, 30, 0},
+	}
+
+	for _, c := range cases {
+		if amount := ch.modifyDamage(c.damageType, c.amount); amount != c.expected {
+			t.Errorf("expected %d damage of type %d to become %d, got %d", c.amount, c.damageType, c.expected, amount)
+		}
+	}
+
+	ch.Affected |= AFFECT_SANCTUARY
+
+	if amount := ch.modifyDamage(DamageTypeFire, 30); amount != 10 {
+		t.Errorf("expected sanctuary and resistance to leave 10 damage, got %d", amount)
+	}
+}
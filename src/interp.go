@@ -9,6 +9,7 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/dop251/goja"
@@ -21,80 +22,138 @@ type Command struct {
 	Scripted     bool
 	Callback     goja.Callable
 	Hidden       bool
+	Channel      bool
+
+	/* The lowest position the command can be used from, such as PositionResting */
+	MinimumPosition int
+
+	/* Like ROM's "must be typed in full": never reached by an abbreviation */
+	ExactOnly bool
 }
 
 var CommandTable map[string]Command
 
+/*
+ * Commands tried first, in this order, when input is only an abbreviation, so that
+ * "sc" means score rather than scan; everything else follows alphabetically.
+ */
+var CommandPriority = []string{
+	"north", "east", "south", "west", "up", "down",
+	"look", "kill", "cast", "get", "take", "put", "inventory", "equipment", "score",
+	"say", "tell", "reply", "wear", "remove", "drop", "give", "flee",
+	"who", "practice", "skills", "spells",
+}
+
 /* Magic method will be called automatically to populate command table global */
 func init() {
 	CommandTable = make(map[string]Command)
 
 	/* Commands table entries which are manually initialized, grouped by file */
 
+	/* account.go */
+	CommandTable["account"] = Command{Name: "account", CmdFunc: do_account}
+	CommandTable["password"] = Command{Name: "password", CmdFunc: do_password}
+
 	/* act_comm.go */
 	CommandTable["afk"] = Command{Name: "afk", CmdFunc: do_afk}
-	CommandTable["group"] = Command{Name: "group", CmdFunc: do_group}
-	CommandTable["ooc"] = Command{Name: "ooc", CmdFunc: do_ooc}
-	CommandTable["say"] = Command{Name: "say", CmdFunc: do_say}
+	CommandTable["group"] = Command{Name: "group", CmdFunc: do_group, MinimumPosition: PositionSleeping}
+	CommandTable["ooc"] = Command{Name: "ooc", CmdFunc: do_ooc, MinimumPosition: PositionSleeping}
+	CommandTable["reply"] = Command{Name: "reply", CmdFunc: do_reply, MinimumPosition: PositionResting}
+	CommandTable["say"] = Command{Name: "say", CmdFunc: do_say, MinimumPosition: PositionResting}
 	CommandTable["save"] = Command{Name: "save", CmdFunc: do_save}
+	CommandTable["tell"] = Command{Name: "tell", CmdFunc: do_tell, MinimumPosition: PositionResting}
 
 	/* act_info.go */
 	CommandTable["affect"] = Command{Name: "affect", CmdFunc: do_affect}
+	CommandTable["commands"] = Command{Name: "commands", CmdFunc: do_commands}
 	CommandTable["help"] = Command{Name: "help", CmdFunc: do_help}
-	CommandTable["look"] = Command{Name: "look", CmdFunc: do_look}
-	CommandTable["quit"] = Command{Name: "quit", CmdFunc: do_quit}
-	CommandTable["scan"] = Command{Name: "scan", CmdFunc: do_scan}
+	CommandTable["look"] = Command{Name: "look", CmdFunc: do_look, MinimumPosition: PositionResting}
+	CommandTable["quit"] = Command{Name: "quit", CmdFunc: do_quit, ExactOnly: true}
+	CommandTable["scan"] = Command{Name: "scan", CmdFunc: do_scan, MinimumPosition: PositionResting}
 	CommandTable["score"] = Command{Name: "score", CmdFunc: do_score}
 	CommandTable["who"] = Command{Name: "who", CmdFunc: do_who}
 	CommandTable["time"] = Command{Name: "time", CmdFunc: do_time}
 
 	/* act_move.go */
-	CommandTable["north"] = Command{Name: "north", CmdFunc: do_north}
-	CommandTable["east"] = Command{Name: "east", CmdFunc: do_east}
-	CommandTable["south"] = Command{Name: "south", CmdFunc: do_south}
-	CommandTable["west"] = Command{Name: "west", CmdFunc: do_west}
-	CommandTable["up"] = Command{Name: "up", CmdFunc: do_up}
-	CommandTable["down"] = Command{Name: "down", CmdFunc: do_down}
-	CommandTable["follow"] = Command{Name: "follow", CmdFunc: do_follow}
-	CommandTable["open"] = Command{Name: "open", CmdFunc: do_open}
-	CommandTable["close"] = Command{Name: "close", CmdFunc: do_close}
+	CommandTable["north"] = Command{Name: "north", CmdFunc: do_north, MinimumPosition: PositionStanding}
+	CommandTable["east"] = Command{Name: "east", CmdFunc: do_east, MinimumPosition: PositionStanding}
+	CommandTable["south"] = Command{Name: "south", CmdFunc: do_south, MinimumPosition: PositionStanding}
+	CommandTable["west"] = Command{Name: "west", CmdFunc: do_west, MinimumPosition: PositionStanding}
+	CommandTable["up"] = Command{Name: "up", CmdFunc: do_up, MinimumPosition: PositionStanding}
+	CommandTable["down"] = Command{Name: "down", CmdFunc: do_down, MinimumPosition: PositionStanding}
+	CommandTable["follow"] = Command{Name: "follow", CmdFunc: do_follow, MinimumPosition: PositionResting}
+	CommandTable["open"] = Command{Name: "open", CmdFunc: do_open, MinimumPosition: PositionResting}
+	CommandTable["close"] = Command{Name: "close", CmdFunc: do_close, MinimumPosition: PositionResting}
 
 	/* act_obj.go */
 	CommandTable["equipment"] = Command{Name: "equipment", CmdFunc: do_equipment}
 	CommandTable["inventory"] = Command{Name: "inventory", CmdFunc: do_inventory}
-	CommandTable["wear"] = Command{Name: "wear", CmdFunc: do_wear}
-	CommandTable["remove"] = Command{Name: "remove", CmdFunc: do_remove}
-	CommandTable["give"] = Command{Name: "give", CmdFunc: do_give}
-	CommandTable["take"] = Command{Name: "take", CmdFunc: do_take}
-	CommandTable["drop"] = Command{Name: "drop", CmdFunc: do_drop}
-	CommandTable["put"] = Command{Name: "put", CmdFunc: do_put}
-	CommandTable["use"] = Command{Name: "use", CmdFunc: do_use}
+	CommandTable["wear"] = Command{Name: "wear", CmdFunc: do_wear, MinimumPosition: PositionResting}
+	CommandTable["remove"] = Command{Name: "remove", CmdFunc: do_remove, MinimumPosition: PositionResting}
+	CommandTable["give"] = Command{Name: "give", CmdFunc: do_give, MinimumPosition: PositionResting}
+	CommandTable["take"] = Command{Name: "take", CmdFunc: do_take, MinimumPosition: PositionResting}
+	CommandTable["drop"] = Command{Name: "drop", CmdFunc: do_drop, MinimumPosition: PositionResting}
+	CommandTable["put"] = Command{Name: "put", CmdFunc: do_put, MinimumPosition: PositionResting}
+	CommandTable["use"] = Command{Name: "use", CmdFunc: do_use, MinimumPosition: PositionResting}
 
 	/* act_wiz.go */
+	CommandTable["copyover"] = Command{Name: "copyover", CmdFunc: do_copyover, MinimumLevel: LevelAdmin, ExactOnly: true}
 	CommandTable["exec"] = Command{Name: "exec", CmdFunc: do_exec, MinimumLevel: LevelAdmin}
+	CommandTable["fights"] = Command{Name: "fights", CmdFunc: do_fights, MinimumLevel: LevelHero + 1}
 	CommandTable["goto"] = Command{Name: "goto", CmdFunc: do_goto, MinimumLevel: LevelHero + 1}
 	CommandTable["mem"] = Command{Name: "mem", CmdFunc: do_mem, MinimumLevel: LevelAdmin}
 	CommandTable["mlist"] = Command{Name: "mlist", CmdFunc: do_mlist, MinimumLevel: LevelAdmin}
 	CommandTable["path"] = Command{Name: "path", CmdFunc: do_path, MinimumLevel: LevelAdmin}
 	CommandTable["peace"] = Command{Name: "peace", CmdFunc: do_peace, MinimumLevel: LevelHero + 1}
-	CommandTable["purge"] = Command{Name: "purge", CmdFunc: do_purge, MinimumLevel: LevelHero + 2}
+	CommandTable["purge"] = Command{Name: "purge", CmdFunc: do_purge, MinimumLevel: LevelHero + 2, ExactOnly: true}
 	CommandTable["script"] = Command{Name: "script", CmdFunc: do_script, MinimumLevel: LevelAdmin}
-	CommandTable["shutdown"] = Command{Name: "shutdown", CmdFunc: do_shutdown, MinimumLevel: LevelAdmin}
+	CommandTable["shutdown"] = Command{Name: "shutdown", CmdFunc: do_shutdown, MinimumLevel: LevelAdmin, ExactOnly: true}
 	CommandTable["zones"] = Command{Name: "zones", CmdFunc: do_zones, MinimumLevel: LevelHero + 1}
 	CommandTable["webhook"] = Command{Name: "webhook", CmdFunc: do_webhook, MinimumLevel: LevelAdmin}
-	CommandTable["wiznet"] = Command{Name: "wiznet", CmdFunc: do_wiznet, MinimumLevel: LevelAdmin}
+	CommandTable["wiznet"] = Command{Name: "wiznet", CmdFunc: do_wiznet, MinimumLevel: LevelAdmin, MinimumPosition: PositionSleeping}
+
+	/* alias.go */
+	CommandTable["alias"] = Command{Name: "alias", CmdFunc: do_alias}
+	CommandTable["unalias"] = Command{Name: "unalias", CmdFunc: do_unalias}
+
+	/* ban.go */
+	CommandTable["ban"] = Command{Name: "ban", CmdFunc: do_ban, MinimumLevel: LevelAdmin}
+
+	/* channel.go */
+	CommandTable["channels"] = Command{Name: "channels", CmdFunc: do_channels}
+
+	/* channel_history.go */
+	CommandTable["history"] = Command{Name: "history", CmdFunc: do_history}
+
+	/* ignore.go */
+	CommandTable["ignore"] = Command{Name: "ignore", CmdFunc: do_ignore}
+
+	/* mail.go */
+	CommandTable["mail"] = Command{Name: "mail", CmdFunc: do_mail}
+
+	/* position.go */
+	CommandTable["rest"] = Command{Name: "rest", CmdFunc: do_rest, MinimumPosition: PositionSleeping}
+	CommandTable["sit"] = Command{Name: "sit", CmdFunc: do_sit, MinimumPosition: PositionSleeping}
+	CommandTable["sleep"] = Command{Name: "sleep", CmdFunc: do_sleep, MinimumPosition: PositionSleeping}
+	CommandTable["stand"] = Command{Name: "stand", CmdFunc: do_stand, MinimumPosition: PositionSleeping}
+	CommandTable["wake"] = Command{Name: "wake", CmdFunc: do_wake, MinimumPosition: PositionSleeping}
+
+	/* social.go */
+	CommandTable["socials"] = Command{Name: "socials", CmdFunc: do_socials}
 
 	/* fight.go */
-	CommandTable["flee"] = Command{Name: "flee", CmdFunc: do_flee}
-	CommandTable["kill"] = Command{Name: "kill", CmdFunc: do_kill}
+	CommandTable["assist"] = Command{Name: "assist", CmdFunc: do_assist, MinimumPosition: PositionFighting}
+	CommandTable["flee"] = Command{Name: "flee", CmdFunc: do_flee, MinimumPosition: PositionFighting}
+	CommandTable["kill"] = Command{Name: "kill", CmdFunc: do_kill, MinimumPosition: PositionFighting}
+	CommandTable["rescue"] = Command{Name: "rescue", CmdFunc: do_rescue, MinimumPosition: PositionFighting}
 
 	/* magic.go */
-	CommandTable["cast"] = Command{Name: "cast", CmdFunc: do_cast}
+	CommandTable["cast"] = Command{Name: "cast", CmdFunc: do_cast, MinimumPosition: PositionFighting}
 	CommandTable["spells"] = Command{Name: "spells", CmdFunc: do_spells}
 
 	/* shop.go */
-	CommandTable["buy"] = Command{Name: "buy", CmdFunc: do_buy}
-	CommandTable["shop"] = Command{Name: "list", CmdFunc: do_shop}
+	CommandTable["buy"] = Command{Name: "buy", CmdFunc: do_buy, MinimumPosition: PositionResting}
+	CommandTable["shop"] = Command{Name: "list", CmdFunc: do_shop, MinimumPosition: PositionResting}
 
 	/* scripting.go */
 	CommandTable["reload"] = Command{Name: "reload", CmdFunc: do_reload, MinimumLevel: LevelAdmin}
@@ -106,15 +165,17 @@ func init() {
 	/* Aliases */
 	CommandTable["eq"] = Command{Name: "equipment", CmdFunc: do_equipment, Hidden: true}
 	CommandTable["i"] = Command{Name: "inventory", CmdFunc: do_inventory, Hidden: true}
-	CommandTable["k"] = Command{Name: "kill", CmdFunc: do_kill, Hidden: true}
-	CommandTable["l"] = Command{Name: "look", CmdFunc: do_look, Hidden: true}
-	CommandTable["get"] = Command{Name: "take", CmdFunc: do_take, Hidden: true}
-	CommandTable["n"] = Command{Name: "north", CmdFunc: do_north, Hidden: true}
-	CommandTable["e"] = Command{Name: "east", CmdFunc: do_east, Hidden: true}
-	CommandTable["s"] = Command{Name: "south", CmdFunc: do_south, Hidden: true}
-	CommandTable["w"] = Command{Name: "west", CmdFunc: do_west, Hidden: true}
-	CommandTable["u"] = Command{Name: "up", CmdFunc: do_up, Hidden: true}
-	CommandTable["d"] = Command{Name: "down", CmdFunc: do_down, Hidden: true}
+	CommandTable["k"] = Command{Name: "kill", CmdFunc: do_kill, Hidden: true, MinimumPosition: PositionFighting}
+	CommandTable["l"] = Command{Name: "look", CmdFunc: do_look, Hidden: true, MinimumPosition: PositionResting}
+	CommandTable["get"] = Command{Name: "take", CmdFunc: do_take, Hidden: true, MinimumPosition: PositionResting}
+	CommandTable["n"] = Command{Name: "north", CmdFunc: do_north, Hidden: true, MinimumPosition: PositionStanding}
+	CommandTable["e"] = Command{Name: "east", CmdFunc: do_east, Hidden: true, MinimumPosition: PositionStanding}
+	CommandTable["s"] = Command{Name: "south", CmdFunc: do_south, Hidden: true, MinimumPosition: PositionStanding}
+	CommandTable["w"] = Command{Name: "west", CmdFunc: do_west, Hidden: true, MinimumPosition: PositionStanding}
+	CommandTable["u"] = Command{Name: "up", CmdFunc: do_up, Hidden: true, MinimumPosition: PositionStanding}
+	CommandTable["d"] = Command{Name: "down", CmdFunc: do_down, Hidden: true, MinimumPosition: PositionStanding}
+
+	rebuildCommandOrder()
 }
 
 func (ch *Character) Interpret(input string) bool {
@@ -126,20 +187,80 @@ func (ch *Character) Interpret(input string) bool {
 			return true
 		}
 
-		ch.inputCursor += DefaultMaxLines
+		ch.inputCursor += ch.pageLength()
+		return true
+	}
+
+	if !ch.interpretReady(input) {
+		return true
+	}
+
+	/* Hold input behind a wait state, or behind input already held, so it runs in order */
+	if ch.Wait > 0 || len(ch.inputQueue) > 0 {
+		ch.queueInput(input)
 		return true
 	}
 
+	return ch.interpretLine(input)
+}
+
+/*
+ * Whether a line of input may run as a command: a paralysed character can do nothing, and
+ * a script which has taken over the connection, such as the string editor, is handed the
+ * line instead.  Checked for typed input and again for each line held in the input queue.
+ */
+func (ch *Character) interpretReady(input string) bool {
 	if ch.Affected&AFFECT_PARALYSIS != 0 {
 		ch.Send("{YYou are stunned and unable to move!\r\n{x")
-		return true
+		return false
 	}
 
 	if ch.Client != nil && ch.Client.ConnectionHandler != nil {
 		(*ch.Client.ConnectionHandler)(ch.Game.vm.ToValue(ch.Client), ch.Game.vm.ToValue(input))
-		return true
+		return false
+	}
+
+	return true
+}
+
+/* Expand a line of input through the character's aliases and run what it stands for */
+func (ch *Character) interpretLine(input string) bool {
+	if len(ch.aliases) == 0 {
+		return ch.interpretCommands([]string{input})
 	}
 
+	commands, err := expandAliases(ch.aliases, input)
+	if err != nil {
+		ch.Send(fmt.Sprintf("{RYour alias could not be used: %v.{x\r\n", err))
+		return false
+	}
+
+	return ch.interpretCommands(commands)
+}
+
+func (ch *Character) interpretCommands(commands []string) bool {
+	var result bool = true
+
+	for index, command := range commands {
+		/* Stop if an earlier command took the player out of the game */
+		if ch.Client == nil || ch.Client.ConnectionState != ConnectionStatePlaying {
+			break
+		}
+
+		/* An earlier command lagged the character; the rest wait their turn */
+		if ch.Wait > 0 {
+			ch.requeueCommands(commands[index:])
+			break
+		}
+
+		result = ch.interpretCommand(command)
+	}
+
+	return result
+}
+
+/* Resolve and run a single command, after paging and aliases have been dealt with */
+func (ch *Character) interpretCommand(input string) bool {
 	words := strings.Split(input, " ")
 	if len(words) < 1 {
 		return false
@@ -149,36 +270,156 @@ func (ch *Character) Interpret(input string) bool {
 	command, words := strings.ToLower(words[0]), words[1:]
 	rest := strings.TrimSpace(strings.Join(words, " "))
 
-	val, ok := CommandTable[command]
-	if !ok || (ok && ch.Level < val.MinimumLevel) {
-		/* Send a no such command if there was any command text */
-		if len(command) > 0 {
-			/* As a fallback, see if this command matches any proficiency which has a registered handler. */
-			prof := ch.FindProficiencyByName(command)
-			if prof == nil || prof.Proficiency <= 0 || ch.Game.skills[prof.SkillId].Handler == nil {
-				ch.Send(fmt.Sprintf("{RAlas, there is no such command: %s{x\r\n", command))
-				return false
-			}
-
-			if ch.Game.skills[prof.SkillId].Intent == SkillIntentOffensive && ch.Room.Flags&ROOM_SAFE != 0 {
-				ch.Send("You can't do that here.\r\n")
-				return false
-			}
-
-			(*ch.Game.skills[prof.SkillId].Handler)(ch.Game.vm.ToValue(prof), ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(rest))
-		} else {
-			/* We'll still want a prompt on no input */
-			ch.Send("\r\n")
-		}
+	if command == "" {
+		/* We'll still want a prompt on no input */
+		ch.Send("\r\n")
+		return true
+	}
 
+	/* Exact names win over abbreviations, whether command, skill or social */
+	if val, ok := ch.findCommand(command); ok {
+		ch.runCommand(val, rest)
 		return true
 	}
+
+	if prof := ch.findSkillHandler(command, false); prof != nil {
+		return ch.useSkill(prof, rest)
+	}
+
+	if social := ch.Game.FindSocial(command); social != nil {
+		ch.performSocial(social, rest)
+		return true
+	}
+
+	if val, ok := ch.findCommandByPrefix(command); ok {
+		ch.runCommand(val, rest)
+		return true
+	}
+
+	if prof := ch.findSkillHandler(command, true); prof != nil {
+		return ch.useSkill(prof, rest)
+	}
+
+	if social := ch.Game.findSocialByPrefix(command); social != nil {
+		ch.performSocial(social, rest)
+		return true
+	}
+
+	ch.Send(fmt.Sprintf("{RAlas, there is no such command: %s{x\r\n", command))
+	return false
+}
+
+func (ch *Character) runCommand(val Command, arguments string) {
+	if ch.Position < val.MinimumPosition {
+		ch.Send(positionRefusal(ch.Position))
+		return
+	}
+
 	/* Call the command func with the remaining command words joined. */
 	if val.Scripted {
-		val.Callback(ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(rest))
-		return true
+		val.Callback(ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(arguments))
+		return
 	}
 
-	val.CmdFunc(ch, rest)
+	val.CmdFunc(ch, arguments)
+}
+
+func (ch *Character) useSkill(prof *Proficiency, arguments string) bool {
+	if ch.Position < PositionFighting {
+		ch.Send(positionRefusal(ch.Position))
+		return false
+	}
+
+	if ch.Game.skills[prof.SkillId].Intent == SkillIntentOffensive && ch.Room != nil && ch.Room.Flags&ROOM_SAFE != 0 {
+		ch.Send("You can't do that here.\r\n")
+		return false
+	}
+
+	(*ch.Game.skills[prof.SkillId].Handler)(ch.Game.vm.ToValue(prof), ch.Game.vm.ToValue(ch), ch.Game.vm.ToValue(arguments))
 	return true
 }
+
+func (ch *Character) findCommand(name string) (Command, bool) {
+	val, ok := CommandTable[name]
+	if !ok || ch.Level < val.MinimumLevel {
+		return Command{}, false
+	}
+
+	return val, true
+}
+
+/* Every command table entry in the order abbreviations are tried, kept in step with CommandTable */
+var commandNamesInOrder []string
+
+/* Sort the command table for abbreviation lookups; call again whenever a command is added */
+func rebuildCommandOrder() {
+	priority := make(map[string]int)
+	for index, name := range CommandPriority {
+		priority[name] = index
+	}
+
+	names := make([]string, 0, len(CommandTable))
+	for name := range CommandTable {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i int, j int) bool {
+		pi, iok := priority[names[i]]
+		pj, jok := priority[names[j]]
+
+		if iok != jok {
+			return iok
+		}
+
+		if iok && pi != pj {
+			return pi < pj
+		}
+
+		return names[i] < names[j]
+	})
+
+	commandNamesInOrder = names
+}
+
+func commandOrder() []string {
+	return commandNamesInOrder
+}
+
+func (ch *Character) findCommandByPrefix(prefix string) (Command, bool) {
+	for _, name := range commandOrder() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		if val, ok := ch.findCommand(name); ok && (!val.ExactOnly || name == prefix) {
+			return val, true
+		}
+	}
+
+	return Command{}, false
+}
+
+/* A usable skill with a registered handler, by its full name or a prefix of it */
+func (ch *Character) findSkillHandler(name string, prefix bool) *Proficiency {
+	var found *Proficiency = nil
+	var foundName string
+
+	for _, prof := range ch.Skills {
+		skill := ch.Game.skills[prof.SkillId]
+		if skill == nil || skill.Handler == nil || prof.Proficiency <= 0 {
+			continue
+		}
+
+		if skill.Name == name {
+			return prof
+		}
+
+		/* Of several matching prefixes, take the alphabetically first so the choice never varies */
+		if prefix && strings.HasPrefix(skill.Name, name) && (found == nil || skill.Name < foundName) {
+			found = prof
+			foundName = skill.Name
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+type commandPrefixTest struct {
+	prefix   string
+	level    uint
+	expected string
+}
+
+var commandPrefixTests = []commandPrefixTest{
+	{"inv", 1, "inventory"},
+	{"sc", 1, "score"},
+	{"sca", 1, "scan"},
+	{"l", 1, "look"},
+	{"ge", 1, "take"},
+	{"pu", 1, "put"},
+	{"pu", LevelAdmin, "put"},
+	{"q", 1, ""},
+	{"quit", 1, "quit"},
+	{"shu", LevelAdmin, ""},
+	{"shutdown", LevelAdmin, "shutdown"},
+	{"cop", LevelAdmin, ""},
+	{"shu", 1, ""},
+	{"xyzzy", 1, ""},
+}
+
+func TestFindCommandByPrefix(t *testing.T) {
+	for _, test := range commandPrefixTests {
+		ch := NewCharacter()
+		ch.Level = test.level
+
+		command, ok := ch.findCommandByPrefix(test.prefix)
+		if !ok {
+			if test.expected != "" {
+				t.Errorf("findCommandByPrefix(%q) found nothing, expected %s", test.prefix, test.expected)
+			}
+
+			continue
+		}
+
+		if command.Name != test.expected {
+			t.Errorf("findCommandByPrefix(%q) = %s, expected %s", test.prefix, command.Name, test.expected)
+		}
+	}
+}
+
+func TestCommandOrderIsStable(t *testing.T) {
+	first := commandOrder()
+	second := commandOrder()
+
+	if len(first) != len(second) {
+		t.Fatalf("command order changed length between calls")
+	}
+
+	for index := range first {
+		if first[index] != second[index] {
+			t.Fatalf("command order differs at %d: %s != %s", index, first[index], second[index])
+		}
+	}
+
+	if first[0] != CommandPriority[0] {
+		t.Errorf("command order starts with %s, expected %s", first[0], CommandPriority[0])
+	}
+}
+
+func TestCommandOrderFollowsRegistration(t *testing.T) {
+	defer func() {
+		delete(CommandTable, "zzyzx")
+		rebuildCommandOrder()
+	}()
+
+	CommandTable["zzyzx"] = Command{Name: "zzyzx", CmdFunc: func(ch *Character, arguments string) {}}
+	rebuildCommandOrder()
+
+	ch := NewCharacter()
+
+	command, ok := ch.findCommandByPrefix("zzy")
+	if !ok || command.Name != "zzyzx" {
+		t.Errorf("expected a newly registered command to be found by prefix")
+	}
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/dop251/goja"
 )
 
+/* Pulses a caster is lagged for starting a spell, like the wait skills put on their users */
+const SpellCastWait = 2 * PulsesPerSecond
+
 type CastingContext struct {
 	Casting     *Skill    `json:"casting"`
 	Arguments   string    `json:"arguments"`
@@ -116,6 +119,8 @@ func do_cast(ch *Character, arguments string) {
 	}
 
 	ch.Mana -= prof.Cost
+	ch.markDirty()
+	ch.SetWait(SpellCastWait)
 
 	ch.Casting = &CastingContext{
 		Casting:     found,
@@ -0,0 +1,349 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const MaxMailSubjectLength = 80
+
+type Mail struct {
+	Id          int          `json:"id"`
+	SenderId    int          `json:"senderId"`
+	SenderName  string       `json:"senderName"`
+	RecipientId int          `json:"recipientId"`
+	Subject     string       `json:"subject"`
+	Body        string       `json:"body"`
+	ReadAt      sql.NullTime `json:"readAt"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+/* Mail addressed to a player id, oldest first; the body is left out */
+func (game *Game) listMail(recipientId int) ([]*Mail, error) {
+	rows, err := game.db.Query(`
+		SELECT
+			mail.id,
+			mail.sender_id,
+			player_characters.username,
+			mail.subject,
+			mail.read_at,
+			mail.created_at
+		FROM
+			mail
+		INNER JOIN
+			player_characters ON player_characters.id = mail.sender_id
+		WHERE
+			mail.recipient_id = ?
+		AND
+			mail.deleted_at IS NULL
+		ORDER BY
+			mail.id
+	`, recipientId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	messages := make([]*Mail, 0)
+
+	for rows.Next() {
+		mail := &Mail{RecipientId: recipientId}
+
+		err = rows.Scan(&mail.Id, &mail.SenderId, &mail.SenderName, &mail.Subject, &mail.ReadAt, &mail.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, mail)
+	}
+
+	return messages, nil
+}
+
+func (game *Game) findMail(recipientId int, id int) (*Mail, error) {
+	mail := &Mail{Id: id, RecipientId: recipientId}
+
+	row := game.db.QueryRow(`
+		SELECT
+			mail.sender_id,
+			player_characters.username,
+			mail.subject,
+			mail.body,
+			mail.read_at,
+			mail.created_at
+		FROM
+			mail
+		INNER JOIN
+			player_characters ON player_characters.id = mail.sender_id
+		WHERE
+			mail.id = ?
+		AND
+			mail.recipient_id = ?
+		AND
+			mail.deleted_at IS NULL
+	`, id, recipientId)
+
+	err := row.Scan(&mail.SenderId, &mail.SenderName, &mail.Subject, &mail.Body, &mail.ReadAt, &mail.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return mail, nil
+}
+
+func (game *Game) sendMail(sender *Character, recipientName string, subject string, body string) error {
+	var recipientId int
+
+	row := game.db.QueryRow(`
+		SELECT
+			id
+		FROM
+			player_characters
+		WHERE
+			username = ?
+		AND
+			deleted_at IS NULL
+	`, recipientName)
+
+	err := row.Scan(&recipientId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("no such player")
+		}
+
+		return err
+	}
+
+	_, err = game.db.Exec(`
+		INSERT INTO
+			mail(sender_id, recipient_id, subject, body)
+		VALUES
+			(?, ?, ?, ?)
+	`, sender.Id, recipientId, subject, body)
+	if err != nil {
+		return err
+	}
+
+	recipient := game.findPlayerInWorld(recipientName)
+	if recipient != nil && recipient != sender {
+		recipient.Send(fmt.Sprintf("\r\n{WYou have new mail from %s: %s{x\r\n", sender.Name, subject))
+	}
+
+	return nil
+}
+
+func (game *Game) markMailRead(mail *Mail) error {
+	_, err := game.db.Exec(`
+		UPDATE
+			mail
+		SET
+			read_at = NOW()
+		WHERE
+			id = ?
+		AND
+			read_at IS NULL
+	`, mail.Id)
+
+	return err
+}
+
+func (game *Game) deleteMail(mail *Mail) error {
+	_, err := game.db.Exec(`
+		UPDATE
+			mail
+		SET
+			deleted_at = NOW()
+		WHERE
+			id = ?
+	`, mail.Id)
+
+	return err
+}
+
+/* Tell a player who just entered the game about mail waiting for them */
+func (ch *Character) notifyMail() {
+	messages, err := ch.Game.listMail(ch.Id)
+	if err != nil {
+		log.Printf("Failed to list mail for %s: %v.\r\n", ch.Name, err)
+		return
+	}
+
+	var buf strings.Builder
+	var unread int = 0
+
+	for _, mail := range messages {
+		if mail.ReadAt.Valid {
+			continue
+		}
+
+		unread++
+		buf.WriteString(fmt.Sprintf("{W  #%-5d %-14s %s{x\r\n", mail.Id, mail.SenderName, mail.Subject))
+	}
+
+	if unread == 0 {
+		return
+	}
+
+	ch.Send(fmt.Sprintf("\r\n{WYou have %d unread mail messages:{x\r\n%s{WType 'mail read <#>' to read one.{x\r\n", unread, buf.String()))
+}
+
+/* Open the string editor for a message body, sending it once written */
+func (ch *Character) composeMail(recipientName string, subject string) error {
+	golem := ch.Game.vm.Get("Golem").ToObject(ch.Game.vm)
+
+	editor, ok := goja.AssertFunction(golem.Get("StringEditor"))
+	if !ok {
+		return errors.New("string editor script is not loaded")
+	}
+
+	onComplete := func(call goja.FunctionCall) goja.Value {
+		body := strings.TrimSpace(call.Argument(1).String())
+		if body == "" {
+			ch.Send("{YMessage discarded.{x\r\n")
+			return goja.Undefined()
+		}
+
+		err := ch.Game.sendMail(ch, recipientName, subject, body)
+		if err != nil {
+			ch.Send(fmt.Sprintf("{RYour mail could not be sent: %v{x\r\n", err))
+			return goja.Undefined()
+		}
+
+		ch.Send(fmt.Sprintf("{WMail sent to %s.{x\r\n", recipientName))
+		return goja.Undefined()
+	}
+
+	ch.Send(fmt.Sprintf("{WWriting to %s about: %s{x\r\n", recipientName, subject))
+
+	_, err := editor(goja.Undefined(), ch.Game.vm.ToValue(ch.Client), ch.Game.vm.ToValue(""), ch.Game.vm.ToValue(onComplete))
+	return err
+}
+
+func do_mail(ch *Character, arguments string) {
+	if ch.Client == nil {
+		return
+	}
+
+	firstArgument, arguments := OneArgument(arguments)
+
+	switch strings.ToLower(firstArgument) {
+	case "", "list":
+		messages, err := ch.Game.listMail(ch.Id)
+		if err != nil {
+			ch.Send("A strange force prevents you from checking your mail.\r\n")
+			return
+		}
+
+		if len(messages) == 0 {
+			ch.Send("You have no mail.\r\n")
+			return
+		}
+
+		var buf strings.Builder
+
+		buf.WriteString("{Y    ID# | From           | Sent             | Subject\r\n")
+		buf.WriteString("--------+----------------+------------------+------------------------------\r\n")
+
+		for _, mail := range messages {
+			marker := " "
+			if !mail.ReadAt.Valid {
+				marker = "*"
+			}
+
+			buf.WriteString(fmt.Sprintf("{Y%s%6d | %-14s | %-16s | %s\r\n", marker, mail.Id, mail.SenderName, mail.CreatedAt.Format("2006-01-02 15:04"), mail.Subject))
+		}
+
+		buf.WriteString("{x")
+		ch.Send(buf.String())
+
+	case "read", "delete":
+		secondArgument, _ := OneArgument(arguments)
+
+		id, err := strconv.Atoi(strings.TrimPrefix(secondArgument, "#"))
+		if err != nil {
+			ch.Send(fmt.Sprintf("Usage: mail %s <#>\r\n", strings.ToLower(firstArgument)))
+			return
+		}
+
+		mail, err := ch.Game.findMail(ch.Id, id)
+		if err != nil || mail == nil {
+			ch.Send("You have no such message.\r\n")
+			return
+		}
+
+		if strings.ToLower(firstArgument) == "delete" {
+			err = ch.Game.deleteMail(mail)
+			if err != nil {
+				ch.Send("A strange force prevents you from deleting that message.\r\n")
+				return
+			}
+
+			ch.Send("Message deleted.\r\n")
+			return
+		}
+
+		ch.Send(fmt.Sprintf("{WFrom:    {x%s\r\n{WSent:    {x%s\r\n{WSubject: {x%s\r\n\r\n%s\r\n",
+			mail.SenderName,
+			mail.CreatedAt.Format(time.RFC1123),
+			mail.Subject,
+			mail.Body))
+
+		err = ch.Game.markMailRead(mail)
+		if err != nil {
+			log.Printf("Failed to mark mail %d read: %v.\r\n", mail.Id, err)
+		}
+
+	case "send":
+		recipientName, subject := OneArgument(arguments)
+		recipientName = strings.Title(strings.ToLower(recipientName))
+		subject = strings.TrimSpace(subject)
+
+		if recipientName == "" || subject == "" {
+			ch.Send("Usage: mail send <player> <subject>\r\n")
+			return
+		}
+
+		if len(subject) > MaxMailSubjectLength {
+			ch.Send(fmt.Sprintf("Please keep the subject under %d characters.\r\n", MaxMailSubjectLength))
+			return
+		}
+
+		exists, err := ch.Game.playerNameExists(recipientName)
+		if err != nil || !exists {
+			ch.Send("No player by that name exists.\r\n")
+			return
+		}
+
+		err = ch.composeMail(recipientName, subject)
+		if err != nil {
+			log.Printf("Failed to open the string editor for mail: %v.\r\n", err)
+			ch.Send("A strange force prevents you from writing mail.\r\n")
+		}
+
+	default:
+		ch.Send("{WMail commands:\r\n" +
+			"{Glist                       - {glist your mail{x\r\n" +
+			"{Gread <#>                   - {gread a message{x\r\n" +
+			"{Gsend <player> <subject>    - {gwrite a message in the string editor{x\r\n" +
+			"{Gdelete <#>                 - {gdelete a message{x\r\n")
+	}
+}
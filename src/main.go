@@ -13,6 +13,8 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
@@ -28,18 +30,51 @@ func main() {
 		os.Exit(1)
 	}
 
-	app, err := net.Listen("tcp", fmt.Sprintf(":%d", Config.Port))
+	/* If we were exec'd by a copyover, pick up the listener and sessions it left us */
+	manifest, err := LoadCopyoverManifest()
+	if err != nil {
+		log.Printf("Unable to read copyover manifest: %v.\r\n", err)
+	}
+
+	var app net.Listener
+
+	if manifest != nil {
+		app, err = manifest.Listener()
+	} else {
+		app, err = net.Listen("tcp", fmt.Sprintf(":%d", Config.Port))
+	}
+
 	if err != nil {
 		log.Println(err)
 		os.Exit(1)
 	}
 
+	game.listener = app
+
+	if manifest != nil {
+		game.recoverCopyover(manifest)
+	}
+
+	/* Save everyone and exit cleanly when the container or init system asks us to stop */
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		<-signals
+		game.shutdownRequest <- true
+	}()
+
 	/* Spawn the webhook-handling goroutine */
 	go game.handleWebhooks()
 
 	/* Start the game loop */
 	go game.Run()
 
+	/* Optionally accept encrypted sessions on a second port */
+	if Config.TLSConfiguration.Enabled {
+		go game.listenTLS()
+	}
+
 	log.Printf("Golem is ready to rock and roll on port %d.\r\n", Config.Port)
 
 	/* Spawn a new goroutine for each new client. */
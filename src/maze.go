@@ -240,6 +240,18 @@ func (maze *MazeGrid) reify(z int) {
 	}
 }
 
+func clampMazeViewport(start int, limit int) int {
+	if start > limit {
+		start = limit
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	return start
+}
+
 func (ch *Character) CreateMazeMap() string {
 	var output strings.Builder
 
@@ -252,8 +264,30 @@ func (ch *Character) CreateMazeMap() string {
 		return ""
 	}
 
-	for y := 0; y < maze.Height; y++ {
-		for x := 0; x < maze.Width; x++ {
+	/* Crop large mazes to a window around the character that fits their terminal */
+	viewWidth := ch.screenWidth() - 1
+	if viewWidth > maze.Width {
+		viewWidth = maze.Width
+	}
+
+	viewHeight := maze.Height
+	if height := ch.screenHeight(); height > 0 {
+		/* Leave room for the room header, exits and prompt as in the overworld */
+		viewHeight = height - 8
+		if viewHeight < 4 {
+			viewHeight = 4
+		}
+
+		if viewHeight > maze.Height {
+			viewHeight = maze.Height
+		}
+	}
+
+	startX := clampMazeViewport(ch.Room.Cell.X-viewWidth/2, maze.Width-viewWidth)
+	startY := clampMazeViewport(ch.Room.Cell.Y-viewHeight/2, maze.Height-viewHeight)
+
+	for y := startY; y < startY+viewHeight; y++ {
+		for x := startX; x < startX+viewWidth; x++ {
 			if x == ch.Room.Cell.X && y == ch.Room.Cell.Y {
 				output.WriteString("{Y@")
 			} else if x == maze.EntryX && y == maze.EntryY {
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"compress/zlib"
+	"log"
+)
+
+/*
+ * MUD Client Compression Protocol v2: once the client agrees to DO MCCP2, the server
+ * sends IAC SB MCCP2 IAC SE and everything after it is a zlib stream until the server
+ * finishes the stream, at which point output is uncompressed again.
+ *
+ * The compressor is owned by the write pump so that the switch happens at exactly the
+ * right point in the output: the start sequence is queued marked as starting compression,
+ * and everything else only flips the telnet option.
+ */
+var mccp2StartSequence = []byte{TelnetIAC, TelnetSB, TelnetMCCP2, TelnetIAC, TelnetSE}
+
+func init() {
+	TelnetOptionTable[TelnetMCCP2] = &TelnetOption{
+		Name:  "MCCP2",
+		Local: true,
+		OnEnabled: func(client *Client, local bool) {
+			client.startCompression()
+		},
+	}
+}
+
+/* Queue the start sequence, after which the write pump compresses all output */
+func (client *Client) startCompression() {
+	client.queueOutput(ClientOutput{data: mccp2StartSequence, startCompression: true})
+}
+
+/* Write one message from the send queue, starting or ending compression around it as needed */
+func (client *Client) writeOutput(outgoing []byte, startCompression bool) error {
+	if client.compressor != nil && !client.isLocalOptionEnabled(TelnetMCCP2) {
+		/* The client asked us to stop: finish the stream so the rest goes out raw */
+		err := client.endCompression()
+		if err != nil {
+			return err
+		}
+	}
+
+	if client.compressor != nil {
+		/* Already compressing, so there's no new stream to announce */
+		if startCompression {
+			return nil
+		}
+
+		_, err := client.compressor.Write(outgoing)
+		if err != nil {
+			return err
+		}
+
+		return client.compressor.Flush()
+	}
+
+	_, err := client.conn.Write(outgoing)
+	if err != nil {
+		return err
+	}
+
+	if startCompression {
+		client.compressor = zlib.NewWriter(client.conn)
+	}
+
+	return nil
+}
+
+func (client *Client) endCompression() error {
+	if client.compressor == nil {
+		return nil
+	}
+
+	err := client.compressor.Close()
+	client.compressor = nil
+
+	if err != nil {
+		log.Printf("Failed to finish MCCP2 stream: %v\r\n", err)
+	}
+
+	return err
+}
@@ -9,18 +9,19 @@ package main
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/getsentry/sentry-go"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const JoinedGameFlavourText = "{WYou have entered the world of Golem.{x"
 const DefaultMaxLines = 50
+const DefaultScreenWidth = 80
+const MinimumScreenWidth = 20
+const MinimumScreenHeight = 8
 
 /* Bust a prompt! */
 func (client *Client) displayPrompt() {
@@ -34,8 +35,11 @@ func (client *Client) displayPrompt() {
 		return
 	}
 
+	client.updateGMCP()
+
 	var prompt bytes.Buffer
-	if client.Character.outputCursor >= DefaultMaxLines && client.Character.inputCursor >= DefaultMaxLines {
+	pageLength := client.Character.pageLength()
+	if client.Character.outputCursor >= pageLength && client.Character.inputCursor >= pageLength {
 		return
 	}
 
@@ -71,6 +75,48 @@ func (client *Client) displayPrompt() {
 	client.Character.Write(prompt.Bytes())
 }
 
+/*
+ * Ask the client to let the server "echo" (it won't) so that a password typed at
+ * the prompt doesn't appear on screen.  Clients which refuse will echo as usual.
+ */
+func (client *Client) beginPasswordEntry() {
+	client.requestLocalOption(TelnetECHO, true)
+}
+
+func (client *Client) endPasswordEntry(output *bytes.Buffer) {
+	if client.isLocalOptionEnabled(TelnetECHO) {
+		/* The client didn't echo the return key either */
+		output.WriteString("\r\n")
+	}
+
+	client.requestLocalOption(TelnetECHO, false)
+}
+
+/* Add a freshly loaded player character and their belongings to the world */
+func (game *Game) enterWorld(ch *Character) {
+	game.Characters.Insert(ch)
+
+	for iter := ch.Inventory.Head; iter != nil; iter = iter.Next {
+		obj := iter.Value.(*ObjectInstance)
+
+		game.Objects.Insert(obj)
+
+		if obj.Contents != nil {
+			for innerIter := obj.Contents.Head; innerIter != nil; innerIter = innerIter.Next {
+				containedObj := innerIter.Value.(*ObjectInstance)
+
+				game.Objects.Insert(containedObj)
+			}
+		}
+	}
+
+	if ch.Room != nil {
+		ch.Room.AddCharacter(ch)
+	}
+
+	game.InvokeNamedEventHandlersWithContextAndArguments("playerEnter", game.vm.ToValue(game), game.vm.ToValue(ch))
+}
+
 func (game *Game) nanny(client *Client, message string) {
 	var output bytes.Buffer
 
@@ -82,6 +128,10 @@ func (game *Game) nanny(client *Client, message string) {
 
 			ctx["remote_address"] = client.conn.RemoteAddr().String()
 
+			if client.account != nil {
+				ctx["account"] = client.account.Name
+			}
+
 			if client.Character != nil {
 				ctx["name"] = client.Character.Name
 				ctx["id"] = client.Character.Id
@@ -103,6 +153,8 @@ func (game *Game) nanny(client *Client, message string) {
 	 *
 	 *
 	 */
+	client.noteActivity()
+
 	switch client.ConnectionState {
 	default:
 		log.Printf("Client is trying to send a message from an invalid or unhandled connection state.\r\n")
@@ -110,60 +162,223 @@ func (game *Game) nanny(client *Client, message string) {
 	case ConnectionStatePlaying:
 		client.Character.Interpret(message)
 
+	case ConnectionStateName:
+		name := strings.Title(strings.ToLower(message))
+		if !game.IsValidPCName(name) {
+			output.WriteString("Invalid account name, please try another.\r\n\r\nAccount name: ")
+			break
+		}
+
+		out := fmt.Sprintf("Guest attempting to login to account: %s\r\n", name)
+		log.Print(out)
+		game.broadcast(out, WiznetBroadcastFilter)
+
+		if ban := game.FindUsernameBan(name); ban != nil {
+			game.reportBanHit(ban, client, fmt.Sprintf("account %s", name))
+			client.refuse("That account has been banned from this game.\r\n")
+			break
+		}
+
+		account, err := game.FindAccountByName(name)
+		if err != nil {
+			panic(err)
+		}
+
+		if account != nil {
+			client.account = account
+			output.WriteString("Password: ")
+			client.beginPasswordEntry()
+			client.ConnectionState = ConnectionStatePassword
+			break
+		}
+
+		client.account = &Account{Game: game, Name: name}
+		client.ConnectionState = ConnectionStateConfirmAccount
+
+		output.WriteString(fmt.Sprintf("No account with that name exists.  Create %s? [y/N] ", name))
+
 	case ConnectionStatePassword:
-		if !game.AttemptLogin(client.Character.Name, message) {
+		client.endPasswordEntry(&output)
+
+		host := remoteHost(client.conn.RemoteAddr())
+
+		if game.logins.IsLockedOut(host, client.account.Name, time.Now()) {
+			out := fmt.Sprintf("Refused login to locked out account %s from %s.\r\n", client.account.Name, host)
+			log.Print(out)
+			game.broadcast(out, WiznetBroadcastFilter)
+
+			client.refuse(output.String() + "Too many failed login attempts; please try again later.\r\n")
+			return
+		}
+
+		if !client.account.CheckPassword(message) {
+			delay := game.logins.RecordFailure(host, client.account.Name, time.Now())
+			client.Delay(int(delay / time.Millisecond))
+
+			out := fmt.Sprintf("Failed login to account %s from %s.\r\n", client.account.Name, host)
+			log.Print(out)
+			game.broadcast(out, WiznetBroadcastFilter)
+
 			client.ConnectionState = ConnectionStateName
-			client.Character = nil
+			client.account = nil
+
+			output.WriteString("Wrong password.\r\n\r\nAccount name: ")
+			break
+		}
+
+		game.logins.RecordSuccess(host, client.account.Name)
+		client.showCharacterMenu(&output)
+
+	case ConnectionStateConfirmAccount:
+		if !strings.HasPrefix(strings.ToLower(message), "y") {
+			client.ConnectionState = ConnectionStateName
+			client.account = nil
+			output.WriteString("\r\nAccount name: ")
+			break
+		}
+
+		client.ConnectionState = ConnectionStateNewPassword
+
+		output.WriteString(fmt.Sprintf("Creating new account %s.\r\n", client.account.Name))
+		output.WriteString("Please choose a password: ")
+		client.beginPasswordEntry()
+
+	case ConnectionStateNewPassword:
+		if len(message) < MinimumPasswordLength {
+			if client.isLocalOptionEnabled(TelnetECHO) {
+				output.WriteString("\r\n")
+			}
+
+			output.WriteString(fmt.Sprintf("Passwords must be at least %d characters long.\r\nPlease choose a password: ", MinimumPasswordLength))
+			break
+		}
+
+		client.ConnectionState = ConnectionStateConfirmPassword
+
+		hash, err := hashPassword(message)
+		if err != nil {
+			log.Println("Failed to hash user password: ", err)
+			return
+		}
+
+		client.account.passwordHash = hash
+		if client.isLocalOptionEnabled(TelnetECHO) {
+			output.WriteString("\r\n")
+		}
+
+		output.WriteString("Please confirm your password: ")
+
+	case ConnectionStateConfirmPassword:
+		if !client.account.CheckPassword(message) {
+			client.ConnectionState = ConnectionStateNewPassword
+			if client.isLocalOptionEnabled(TelnetECHO) {
+				output.WriteString("\r\n")
+			}
 
-			output.WriteString("Wrong password.\r\n\r\nBy what name do you wish to be known? ")
+			output.WriteString("Passwords didn't match.\r\nPlease choose a password: ")
+			break
+		}
+
+		client.endPasswordEntry(&output)
+
+		account, err := game.CreateAccount(client.account.Name, client.account.passwordHash)
+		if err != nil {
+			log.Printf("Unable to create new account %s, dropping connection: %v\r\n", client.account.Name, err)
+			client.conn.Close()
+			break
+		}
+
+		client.account = account
+		client.showCharacterMenu(&output)
+
+	case ConnectionStateChooseCharacter:
+		choice := strings.TrimSpace(message)
+
+		if strings.EqualFold(choice, "new") {
+			client.ConnectionState = ConnectionStateNewCharacter
+			output.WriteString("\r\nBy what name do you wish to be known? ")
+			break
+		}
+
+		characters, err := client.account.Characters()
+		if err != nil {
+			panic(err)
+		}
+
+		chosen := findAccountCharacter(characters, choice)
+		if chosen == nil {
+			if choice != "" {
+				output.WriteString("\r\nThere is no such character on this account.\r\n")
+			}
+
+			client.showCharacterMenu(&output)
+			break
+		}
+
+		if ban := game.FindUsernameBan(chosen.Name); ban != nil {
+			game.reportBanHit(ban, client, fmt.Sprintf("character %s", chosen.Name))
+			output.WriteString("\r\nThat character has been banned from this game.\r\n")
+			client.showCharacterMenu(&output)
 			break
 		}
 
 		for other := range game.clients {
-			if other != client && other.Character != nil && other.Character.Name == client.Character.Name {
+			if other != client && other.Character != nil && other.Character.Name == chosen.Name {
 				delete(game.clients, other)
 
 				other.conn.Close()
 			}
 		}
 
-		if game.checkReconnect(client, client.Character.Name) {
+		if game.checkReconnect(client, chosen.Name) {
 			break
 		}
 
+		character, room, err := game.FindPlayerByName(chosen.Name)
+		if err != nil {
+			panic(err)
+		}
+
+		if character == nil {
+			output.WriteString("\r\nThat character could not be loaded.\r\n")
+			client.showCharacterMenu(&output)
+			break
+		}
+
+		client.Character = character
+		client.Character.Flags |= CHAR_IS_PLAYER
+		client.Character.Room = room
 		client.Character.Client = client
 		client.ConnectionState = ConnectionStateMessageOfTheDay
 		output.WriteString(string(Config.motd))
 		output.WriteString("[ Press return to continue ]")
 
-	case ConnectionStateName:
+	case ConnectionStateNewCharacter:
 		name := strings.Title(strings.ToLower(message))
 		if !game.IsValidPCName(name) {
 			output.WriteString("Invalid name, please try another.\r\n\r\nBy what name do you wish to be known? ")
 			break
 		}
 
-		out := fmt.Sprintf("Guest attempting to login with name: %s\r\n", name)
-		log.Print(out)
-		game.broadcast(out, WiznetBroadcastFilter)
+		if game.FindUsernameBan(name) != nil {
+			output.WriteString("That name is not allowed, please try another.\r\n\r\nBy what name do you wish to be known? ")
+			break
+		}
 
-		character, room, err := game.FindPlayerByName(name)
+		exists, err := game.playerNameExists(name)
 		if err != nil {
 			panic(err)
 		}
 
-		if character != nil {
-			client.Character = character
-			client.Character.Flags |= CHAR_IS_PLAYER
-			output.WriteString("Password: ")
-			client.Character.Room = room
-			client.ConnectionState = ConnectionStatePassword
+		if exists {
+			output.WriteString("That name is already taken, please try another.\r\n\r\nBy what name do you wish to be known? ")
 			break
 		}
 
 		client.Character = NewCharacter()
 		client.Character.Game = game
 		client.Character.Client = client
+		client.Character.AccountId = client.account.Id
 		client.Character.Name = name
 		client.Character.Level = 1
 		client.Character.Flags |= CHAR_IS_PLAYER
@@ -188,40 +403,12 @@ func (game *Game) nanny(client *Client, message string) {
 
 	case ConnectionStateConfirmName:
 		if !strings.HasPrefix(strings.ToLower(message), "y") {
-			client.ConnectionState = ConnectionStateName
-			client.Character.Name = UnauthenticatedUsername
-			output.WriteString("\r\nBy what name do you wish to be known? ")
+			client.Character = nil
+			client.showCharacterMenu(&output)
 			break
 		}
 
-		client.ConnectionState = ConnectionStateNewPassword
-
 		output.WriteString(fmt.Sprintf("Creating new character %s.\r\n", client.Character.Name))
-		output.WriteString("Please choose a password: ")
-
-	case ConnectionStateNewPassword:
-		client.ConnectionState = ConnectionStateConfirmPassword
-
-		sha256Sum := sha256.Sum256([]byte(message + Config.HashSalt))
-		saltedHash := hex.EncodeToString(sha256Sum[:])
-		ciphertext, err := bcrypt.GenerateFromPassword([]byte(saltedHash), 10)
-		if err != nil {
-			log.Println("Failed to bcrypt user password: ", err)
-			return
-		}
-
-		client.Character.temporaryHash = string(ciphertext)
-		output.WriteString("Please confirm your password: ")
-
-	case ConnectionStateConfirmPassword:
-		sha256Sum := sha256.Sum256([]byte(message + Config.HashSalt))
-		saltedHash := hex.EncodeToString(sha256Sum[:])
-
-		if bcrypt.CompareHashAndPassword([]byte(client.Character.temporaryHash), []byte(saltedHash)) != nil {
-			client.ConnectionState = ConnectionStateNewPassword
-			output.WriteString("Passwords didn't match.\r\nPlease choose a password: ")
-			break
-		}
 
 		client.ConnectionState = ConnectionStateChooseRace
 		output.WriteString("Please choose a race from the following options:\r\n")
@@ -411,25 +598,9 @@ func (game *Game) nanny(client *Client, message string) {
 	case ConnectionStateMessageOfTheDay:
 		client.ConnectionState = ConnectionStatePlaying
 
-		game.Characters.Insert(client.Character)
-
-		for iter := client.Character.Inventory.Head; iter != nil; iter = iter.Next {
-			obj := iter.Value.(*ObjectInstance)
-
-			game.Objects.Insert(obj)
-
-			if obj.Contents != nil {
-				for innerIter := obj.Contents.Head; innerIter != nil; innerIter = innerIter.Next {
-					containedObj := innerIter.Value.(*ObjectInstance)
-
-					game.Objects.Insert(containedObj)
-				}
-			}
-		}
+		game.enterWorld(client.Character)
 
 		if client.Character.Room != nil {
-			client.Character.Room.AddCharacter(client.Character)
-
 			out := fmt.Sprintf("{W%s has entered the game.{x\r\n", client.Character.Name)
 
 			game.broadcast(out, func(character *Character) bool {
@@ -445,6 +616,7 @@ func (game *Game) nanny(client *Client, message string) {
 		}
 
 		do_look(client.Character, "")
+		client.Character.notifyMail()
 	}
 
 	if client.ConnectionState != ConnectionStatePlaying && output.Len() > 0 {
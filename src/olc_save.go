@@ -96,7 +96,10 @@ func (ch *Character) Sync() error {
 			stat_wis = ?,
 			stat_con = ?,
 			stat_cha = ?,
-			stat_lck = ?
+			stat_lck = ?,
+			resist = ?,
+			immune = ?,
+			suscept = ?
 		WHERE
 			id = ?
 	`,
@@ -123,6 +126,9 @@ func (ch *Character) Sync() error {
 		ch.Stats[STAT_CONSTITUTION],
 		ch.Stats[STAT_CHARISMA],
 		ch.Stats[STAT_LUCK],
+		ch.Resist,
+		ch.Immune,
+		ch.Suscept,
 		ch.Id)
 	if err != nil {
 		return err
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+ * Passwords are stored in the PHC string format, self-describing the algorithm and its
+ * cost so that either can change without invalidating existing hashes:
+ *
+ *     $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+ *
+ * Hashes from before this format are bcrypt over a SHA-256 of the password and the
+ * global Config.HashSalt; they are still accepted, and replaced on the next login.
+ */
+const PasswordArgon2idPrefix = "$argon2id$"
+const PasswordSaltLength = 16
+const PasswordKeyLength = 32
+const MinimumPasswordLength = 5
+
+var ErrMalformedPasswordHash = errors.New("malformed password hash")
+
+type argon2idParameters struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func currentPasswordParameters() argon2idParameters {
+	return argon2idParameters{
+		memory:      Config.PasswordConfiguration.Memory,
+		iterations:  Config.PasswordConfiguration.Iterations,
+		parallelism: Config.PasswordConfiguration.Parallelism,
+	}
+}
+
+func hashPassword(password string) (string, error) {
+	params := currentPasswordParameters()
+
+	salt := make([]byte, PasswordSaltLength)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, PasswordKeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordArgon2idPrefix,
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func decodeArgon2idHash(hash string) (argon2idParameters, []byte, []byte, error) {
+	var params argon2idParameters
+	var version int
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrMalformedPasswordHash
+	}
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedPasswordHash
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil {
+		return params, nil, nil, ErrMalformedPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+func verifyLegacyPassword(hash string, password string) bool {
+	sha256Sum := sha256.Sum256([]byte(password + Config.HashSalt))
+	saltedHash := hex.EncodeToString(sha256Sum[:])
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(saltedHash)) == nil
+}
+
+/*
+ * Check a password against a stored hash of any supported version.  rehash reports
+ * that the password was right but the hash is outdated and should be replaced.
+ */
+func verifyPassword(hash string, password string) (ok bool, rehash bool) {
+	if !strings.HasPrefix(hash, PasswordArgon2idPrefix) {
+		return verifyLegacyPassword(hash, password), true
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false, false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false
+	}
+
+	return true, params != currentPasswordParameters()
+}
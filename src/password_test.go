@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordRoundTrip(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, PasswordArgon2idPrefix) {
+		t.Errorf("hash %q is not in argon2id format", hash)
+	}
+
+	ok, rehash := verifyPassword(hash, "correct horse")
+	if !ok || rehash {
+		t.Errorf("verifyPassword(correct) = %v, %v; want true, false", ok, rehash)
+	}
+
+	ok, _ = verifyPassword(hash, "battery staple")
+	if ok {
+		t.Errorf("verifyPassword accepted the wrong password")
+	}
+
+	other, _ := hashPassword("correct horse")
+	if other == hash {
+		t.Errorf("two hashes of the same password share a salt")
+	}
+}
+
+func TestLegacyPasswordIsRehashed(t *testing.T) {
+	sha256Sum := sha256.Sum256([]byte("hunter2" + Config.HashSalt))
+	legacy, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(sha256Sum[:])), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt failed: %v", err)
+	}
+
+	ok, rehash := verifyPassword(string(legacy), "hunter2")
+	if !ok || !rehash {
+		t.Errorf("verifyPassword(legacy) = %v, %v; want true, true", ok, rehash)
+	}
+
+	ok, _ = verifyPassword(string(legacy), "hunter3")
+	if ok {
+		t.Errorf("verifyPassword accepted the wrong legacy password")
+	}
+}
+
+func TestPasswordRehashedWhenCostChanges(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+
+	previous := Config.PasswordConfiguration
+	defer func() { Config.PasswordConfiguration = previous }()
+
+	Config.PasswordConfiguration.Iterations++
+
+	ok, rehash := verifyPassword(hash, "correct horse")
+	if !ok || !rehash {
+		t.Errorf("verifyPassword after cost change = %v, %v; want true, true", ok, rehash)
+	}
+}
+
+func TestMalformedPasswordHash(t *testing.T) {
+	for _, hash := range []string{"$argon2id$", "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=18$m=8,t=1,p=1$c2FsdA$a2V5"} {
+		ok, _ := verifyPassword(hash, "anything")
+		if ok {
+			t.Errorf("verifyPassword accepted malformed hash %q", hash)
+		}
+	}
+}
+
+func TestPasswordArgumentsKeepCase(t *testing.T) {
+	hash, err := hashPassword("OldPass")
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+
+	account := &Account{passwordHash: hash}
+
+	current, replacement := passwordArguments("  OldPass   NewPass\"1 ")
+	if !account.CheckPassword(current) {
+		t.Fatalf("expected the current password %q to be accepted as typed", current)
+	}
+
+	account.passwordHash, err = hashPassword(replacement)
+	if err != nil {
+		t.Fatalf("hashPassword failed: %v", err)
+	}
+
+	if !account.CheckPassword("NewPass\"1") {
+		t.Errorf("expected the new password to be checked in its original case")
+	}
+
+	if account.CheckPassword("newpass\"1") {
+		t.Errorf("expected a lowercased new password to be refused")
+	}
+}
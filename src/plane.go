@@ -35,6 +35,9 @@ type Plane struct {
 	Map     *Map        `json:"map"`
 	Maze    *MazeGrid   `json:"maze"`
 	Portals *LinkedList `json:"portals"`
+
+	/* Terrain has changed since the blob was last saved */
+	Dirty bool `json:"dirty"`
 }
 
 type District struct {
@@ -197,10 +200,22 @@ func (plane *Plane) SaveBlob() error {
 		return err
 	}
 
+	plane.Dirty = false
+
 	log.Printf("Saved blob %d.\r\n", plane.Id)
 	return nil
 }
 
+/* Change a single terrain cell, marking the plane for saving */
+func (plane *Plane) SetTerrain(x int, y int, z int, terrain int) {
+	if plane.Map == nil || z < 0 || z >= len(plane.Map.Layers) || y < 0 || y >= plane.Height || x < 0 || x >= plane.Width {
+		return
+	}
+
+	plane.Map.Layers[z].Terrain[y][x] = terrain
+	plane.Dirty = true
+}
+
 // Fill the source_value field for this plane with an appropriately sized binary blob of zeroes
 func (plane *Plane) InitializeBlob() ([]byte, int, error) {
 	log.Printf("Initializing new blob for plane %d.\r\n", plane.Id)
@@ -242,6 +257,27 @@ func (plane *Plane) InitializeBlob() ([]byte, int, error) {
 	return bytes, plane.Depth * plane.Width * plane.Height, nil
 }
 
+/* Size of the overworld camera, shrunk to fit the character's terminal if need be */
+func (ch *Character) planeMapViewport() (int, int) {
+	var cameraWidth int = 48
+	var cameraHeight int = 18
+
+	/* Stay clear of the last column so terminals don't wrap the line */
+	if width := ch.screenWidth() - 1; width < cameraWidth {
+		cameraWidth = width
+	}
+
+	/* Leave room for the room header, exits and prompt around the map */
+	if height := ch.screenHeight(); height > 0 && height-8 < cameraHeight {
+		cameraHeight = height - 8
+		if cameraHeight < 4 {
+			cameraHeight = 4
+		}
+	}
+
+	return cameraWidth, cameraHeight
+}
+
 func (ch *Character) CreatePlaneMap() string {
 	if ch.Room == nil || ch.Room.Plane == nil {
 		return "Error retrieving plane map\r\n"
@@ -249,8 +285,7 @@ func (ch *Character) CreatePlaneMap() string {
 
 	var buf strings.Builder
 
-	var cameraWidth int = 48
-	var cameraHeight int = 18
+	cameraWidth, cameraHeight := ch.planeMapViewport()
 	var cameraRange int = 9
 
 	cameraX := ch.Room.X
@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"fmt"
+)
+
+var PositionNameTable map[int]string = map[int]string{
+	PositionDead:     "dead",
+	PositionStunned:  "stunned",
+	PositionSleeping: "sleeping",
+	PositionResting:  "resting",
+	PositionSitting:  "sitting",
+	PositionFighting: "fighting",
+	PositionStanding: "standing",
+}
+
+/* Rest is recovery: the lower a character settles, the faster they regenerate */
+func positionRegenMultiplier(position int) float64 {
+	switch position {
+	case PositionSleeping:
+		return 3.0
+
+	case PositionResting:
+		return 2.0
+
+	case PositionSitting:
+		return 1.5
+	}
+
+	return 1.0
+}
+
+/* Why a character in this position can't use a command needing a better one */
+func positionRefusal(position int) string {
+	switch position {
+	case PositionDead:
+		return "Lie still; you are DEAD.\r\n"
+
+	case PositionStunned:
+		return "You are hurt far too bad for that.\r\n"
+
+	case PositionSleeping:
+		return "In your dreams, or what?\r\n"
+
+	case PositionResting:
+		return "Nah... You feel too relaxed...\r\n"
+
+	case PositionSitting:
+		return "Better stand up first.\r\n"
+
+	case PositionFighting:
+		return "No way!  You are still fighting!\r\n"
+	}
+
+	return "You can't do that right now.\r\n"
+}
+
+/*
+ * Furniture objects use their values as:
+ *     value0 - how many characters fit on it at once, with 0 meaning one
+ *     value1 - a percentage bonus to regeneration for those using it
+ */
+func furnitureCapacity(obj *ObjectInstance) int {
+	if obj.Value0 > 0 {
+		return obj.Value0
+	}
+
+	return 1
+}
+
+/* The furniture this character is on, so long as it's still here with them */
+func (ch *Character) furniture() *ObjectInstance {
+	if ch.On == nil || ch.Room == nil || ch.On.InRoom != ch.Room {
+		return nil
+	}
+
+	return ch.On
+}
+
+/* How many characters in the room are using a piece of furniture */
+func (room *Room) furnitureOccupants(obj *ObjectInstance) int {
+	var count int = 0
+
+	for iter := room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch.furniture() == obj {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (ch *Character) regenMultiplier() float64 {
+	multiplier := positionRegenMultiplier(ch.Position)
+
+	if obj := ch.furniture(); obj != nil && obj.Value1 > 0 {
+		multiplier *= 1.0 + float64(obj.Value1)/100.0
+	}
+
+	return multiplier
+}
+
+/* How the character appears in a room listing, after their name */
+func (ch *Character) positionDescription(viewer *Character) string {
+	var verb string
+
+	switch ch.Position {
+	case PositionDead:
+		return "is lying here, dead."
+
+	case PositionStunned:
+		return "is lying here, stunned."
+
+	case PositionSleeping:
+		verb = "sleeping"
+
+	case PositionResting:
+		verb = "resting"
+
+	case PositionSitting:
+		verb = "sitting"
+
+	default:
+		verb = "standing"
+	}
+
+	if obj := ch.furniture(); obj != nil {
+		return fmt.Sprintf("is %s on %s{x.", verb, obj.GetShortDescription(viewer))
+	}
+
+	if verb == "standing" {
+		return "is here."
+	}
+
+	return fmt.Sprintf("is %s here.", verb)
+}
+
+/* Stand a character up, as when they're woken or attacked */
+func (ch *Character) standUp() {
+	ch.Position = PositionStanding
+	ch.On = nil
+}
+
+/*
+ * Settle into a lower position, optionally on a piece of furniture named by arguments.
+ * With no furniture named, a character already on something stays on it.
+ */
+func (ch *Character) settle(position int, arguments string, toSelf string, toSelfOn string, toRoom string, toRoomOn string) {
+	if ch.Room == nil {
+		return
+	}
+
+	if ch.isFighting() {
+		ch.Send("Maybe you should finish fighting first?\r\n")
+		return
+	}
+
+	obj := ch.furniture()
+
+	if arguments != "" {
+		obj = ch.FindObjectInRoom(arguments)
+		if obj == nil {
+			ch.Send("You don't see that here.\r\n")
+			return
+		}
+
+		if obj.ItemType != ItemTypeFurniture {
+			ch.Send("You can't do that on that.\r\n")
+			return
+		}
+
+		if ch.furniture() != obj && ch.Room.furnitureOccupants(obj) >= furnitureCapacity(obj) {
+			ch.Send(fmt.Sprintf("There's no more room on %s{x.\r\n", obj.GetShortDescription(ch)))
+			return
+		}
+	}
+
+	if ch.Position == position && ch.furniture() == obj {
+		ch.Send(fmt.Sprintf("You are already %s.\r\n", PositionNameTable[position]))
+		return
+	}
+
+	ch.Position = position
+	ch.On = obj
+
+	if obj != nil {
+		ch.Send(fmt.Sprintf(toSelfOn, obj.GetShortDescription(ch)))
+	} else {
+		ch.Send(toSelf)
+	}
+
+	for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch == ch {
+			continue
+		}
+
+		if obj != nil {
+			rch.Send(fmt.Sprintf(toRoomOn, ch.GetShortDescriptionUpper(rch), obj.GetShortDescription(rch)))
+		} else {
+			rch.Send(fmt.Sprintf(toRoom, ch.GetShortDescriptionUpper(rch)))
+		}
+	}
+}
+
+func do_sleep(ch *Character, arguments string) {
+	ch.settle(PositionSleeping, arguments,
+		"You go to sleep.\r\n",
+		"You go to sleep on %s{x.\r\n",
+		"\r\n%s{x goes to sleep.\r\n",
+		"\r\n%s{x goes to sleep on %s{x.\r\n")
+}
+
+func do_rest(ch *Character, arguments string) {
+	ch.settle(PositionResting, arguments,
+		"You rest.\r\n",
+		"You rest on %s{x.\r\n",
+		"\r\n%s{x sits down and rests.\r\n",
+		"\r\n%s{x rests on %s{x.\r\n")
+}
+
+func do_sit(ch *Character, arguments string) {
+	ch.settle(PositionSitting, arguments,
+		"You sit down.\r\n",
+		"You sit on %s{x.\r\n",
+		"\r\n%s{x sits down.\r\n",
+		"\r\n%s{x sits on %s{x.\r\n")
+}
+
+func do_stand(ch *Character, arguments string) {
+	if ch.Position == PositionStanding {
+		ch.Send("You are already standing.\r\n")
+		return
+	}
+
+	wasSleeping := ch.Position == PositionSleeping
+	ch.standUp()
+
+	if wasSleeping {
+		ch.Send("You wake and stand up.\r\n")
+	} else {
+		ch.Send("You stand up.\r\n")
+	}
+
+	if ch.Room == nil {
+		return
+	}
+
+	for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch == ch {
+			continue
+		}
+
+		if wasSleeping {
+			rch.Send(fmt.Sprintf("\r\n%s{x wakes and stands up.\r\n", ch.GetShortDescriptionUpper(rch)))
+		} else {
+			rch.Send(fmt.Sprintf("\r\n%s{x stands up.\r\n", ch.GetShortDescriptionUpper(rch)))
+		}
+	}
+}
+
+func do_wake(ch *Character, arguments string) {
+	argument, _ := OneArgument(arguments)
+
+	if argument == "" {
+		if ch.Position != PositionSleeping {
+			ch.Send("You are already awake.\r\n")
+			return
+		}
+
+		do_stand(ch, "")
+		return
+	}
+
+	if ch.Position == PositionSleeping {
+		ch.Send("You are asleep yourself!\r\n")
+		return
+	}
+
+	target := ch.FindCharacterInRoom(argument)
+	if target == nil {
+		ch.Send("They aren't here.\r\n")
+		return
+	}
+
+	if target.Position != PositionSleeping {
+		ch.Send(fmt.Sprintf("%s{x is already awake.\r\n", target.GetShortDescriptionUpper(ch)))
+		return
+	}
+
+	if target.IsIgnoring(ch) {
+		ch.Send(fmt.Sprintf("%s{x doesn't stir.\r\n", target.GetShortDescriptionUpper(ch)))
+		return
+	}
+
+	ch.Send(fmt.Sprintf("You wake %s{x.\r\n", target.GetShortDescription(ch)))
+	target.Send(fmt.Sprintf("\r\n%s{x wakes you.\r\n", ch.GetShortDescriptionUpper(target)))
+	do_stand(target, "")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+func TestRegenMultiplierFavoursLowerPositions(t *testing.T) {
+	positions := []int{PositionStanding, PositionSitting, PositionResting, PositionSleeping}
+
+	for index := 1; index < len(positions); index++ {
+		if positionRegenMultiplier(positions[index]) <= positionRegenMultiplier(positions[index-1]) {
+			t.Errorf("expected %s to regenerate faster than %s", PositionNameTable[positions[index]], PositionNameTable[positions[index-1]])
+		}
+	}
+
+	if positionRegenMultiplier(PositionFighting) != 1.0 {
+		t.Errorf("expected no regeneration bonus while fighting")
+	}
+}
+
+func TestFurnitureRegenBonusOnlyAppliesInSameRoom(t *testing.T) {
+	room := &Room{Characters: NewLinkedList()}
+	bed := &ObjectInstance{ItemType: ItemTypeFurniture, Value1: 50, InRoom: room}
+
+	ch := NewCharacter()
+	ch.Room = room
+	ch.Position = PositionSleeping
+	ch.On = bed
+
+	if multiplier := ch.regenMultiplier(); multiplier != 4.5 {
+		t.Errorf("expected a multiplier of 4.5 sleeping in a bed, got %v", multiplier)
+	}
+
+	bed.InRoom = &Room{Characters: NewLinkedList()}
+
+	if multiplier := ch.regenMultiplier(); multiplier != 3.0 {
+		t.Errorf("expected a multiplier of 3 once the bed was moved away, got %v", multiplier)
+	}
+}
+
+func TestFurnitureCapacityDefaultsToOne(t *testing.T) {
+	if capacity := furnitureCapacity(&ObjectInstance{ItemType: ItemTypeFurniture}); capacity != 1 {
+		t.Errorf("expected a capacity of 1, got %d", capacity)
+	}
+
+	if capacity := furnitureCapacity(&ObjectInstance{ItemType: ItemTypeFurniture, Value0: 4}); capacity != 4 {
+		t.Errorf("expected a capacity of 4, got %d", capacity)
+	}
+}
+
+func TestPositionDescription(t *testing.T) {
+	ch := NewCharacter()
+
+	if description := ch.positionDescription(ch); description != "is here." {
+		t.Errorf("expected a standing character to be \"here\", got %q", description)
+	}
+
+	ch.Position = PositionResting
+
+	if description := ch.positionDescription(ch); description != "is resting here." {
+		t.Errorf("expected a resting description, got %q", description)
+	}
+}
+
+func TestRegenerationMarksPlayersDirty(t *testing.T) {
+	for _, drain := range []func(ch *Character){
+		func(ch *Character) { ch.Health-- },
+		func(ch *Character) { ch.Mana-- },
+		func(ch *Character) { ch.Stamina-- },
+	} {
+		ch := NewCharacter()
+		ch.Flags |= CHAR_IS_PLAYER
+		ch.Health, ch.MaxHealth = 100, 100
+		ch.Mana, ch.MaxMana = 100, 100
+		ch.Stamina, ch.MaxStamina = 100, 100
+
+		drain(ch)
+		ch.onUpdate()
+
+		if !ch.dirty {
+			t.Errorf("expected regeneration to mark the player for saving")
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const CommandsTooQuicklyMessage = "{YYou are sending commands too quickly; slow down.{x\r\n"
+const CommandsDiscardedMessage = "{RYou are sending commands too quickly; some of your input was discarded.{x\r\n"
+
+/*
+ * A classic token bucket: up to capacity commands may arrive back to back, after
+ * which they're admitted at rate per second.
+ */
+type TokenBucket struct {
+	capacity float64
+	rate     float64
+	tokens   float64
+	last     time.Time
+}
+
+func NewTokenBucket(capacity int, rate float64, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		capacity: float64(capacity),
+		rate:     rate,
+		tokens:   float64(capacity),
+		last:     now,
+	}
+}
+
+func (bucket *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	bucket.tokens += elapsed * bucket.rate
+	if bucket.tokens > bucket.capacity {
+		bucket.tokens = bucket.capacity
+	}
+
+	bucket.last = now
+}
+
+/* Spend a token if one is available, otherwise return how long until one will be */
+func (bucket *TokenBucket) Take(now time.Time) time.Duration {
+	bucket.refill(now)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+
+	if bucket.rate <= 0 {
+		return time.Second
+	}
+
+	return time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
+}
+
+func (client *Client) isRateLimitExempt() bool {
+	return atomic.LoadInt32(&client.rateLimitExempt) != 0
+}
+
+func (client *Client) setRateLimitExempt(exempt bool) {
+	var value int32 = 0
+	if exempt {
+		value = 1
+	}
+
+	atomic.StoreInt32(&client.rateLimitExempt, value)
+}
+
+/* Queue a line read from the connection, refusing it if the client is too far ahead */
+func (client *Client) queueInput(input chan<- string, line string) bool {
+	select {
+	case input <- line:
+		return true
+
+	default:
+		return false
+	}
+}
+
+/*
+ * Hand queued input to the game loop one line at a time, honouring any Delay and the
+ * client's token bucket, until stop is closed.
+ */
+func (client *Client) dispatchInput(game *Game, input <-chan string, stop <-chan bool, stopped chan<- bool) {
+	defer close(stopped)
+
+	limiter := NewTokenBucket(Config.RateLimitConfiguration.Burst, Config.RateLimitConfiguration.RefillPerSecond, time.Now())
+	warned := false
+
+	wait := func(duration time.Duration) bool {
+		select {
+		case <-stop:
+			return false
+
+		case <-time.After(duration):
+			return true
+		}
+	}
+
+	for {
+		var line string
+
+		select {
+		case <-stop:
+			return
+
+		case line = <-input:
+		}
+
+		client.delayMutex.Lock()
+		delay := client.delayUntil
+		client.delayMutex.Unlock()
+
+		if time.Now().Before(delay) && !wait(time.Until(delay)) {
+			return
+		}
+
+		if !client.isRateLimitExempt() {
+			for pending := limiter.Take(time.Now()); pending > 0; pending = limiter.Take(time.Now()) {
+				if !warned {
+					warned = true
+					client.Send([]byte(client.TranslateColourCodes(CommandsTooQuicklyMessage)))
+				}
+
+				if !wait(pending) {
+					return
+				}
+			}
+
+			if len(input) == 0 {
+				warned = false
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+
+		case game.clientMessage <- ClientTextMessage{client: client, message: line}:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	now := time.Now()
+	bucket := NewTokenBucket(3, 2, now)
+
+	for i := 0; i < 3; i++ {
+		if wait := bucket.Take(now); wait != 0 {
+			t.Fatalf("take %d within burst: expected no wait, got %v", i+1, wait)
+		}
+	}
+
+	if wait := bucket.Take(now); wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms for the next token, got %v", wait)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	now := time.Now()
+	bucket := NewTokenBucket(2, 4, now)
+
+	bucket.Take(now)
+	bucket.Take(now)
+
+	if wait := bucket.Take(now.Add(250 * time.Millisecond)); wait != 0 {
+		t.Errorf("expected a token after 250ms at 4/s, got wait %v", wait)
+	}
+
+	/* A long idle period refills only up to capacity */
+	later := now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if wait := bucket.Take(later); wait != 0 {
+			t.Fatalf("take %d after idling: expected no wait, got %v", i+1, wait)
+		}
+	}
+
+	if wait := bucket.Take(later); wait == 0 {
+		t.Errorf("expected the bucket to be capped at its capacity")
+	}
+}
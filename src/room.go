@@ -95,6 +95,7 @@ func FindRoomFlag(flag string) *Flag {
 func (room *Room) AddCharacter(ch *Character) {
 	room.Characters.Insert(ch)
 	ch.Room = room
+	ch.markDirty()
 
 	if room.Flags&ROOM_PLANAR != 0 && room.Plane != nil {
 		ch.PlaneIndex = &Point{X: float64(room.X), Y: float64(room.Y), Value: ch}
@@ -164,6 +165,7 @@ func (room *Room) AddCharacter(ch *Character) {
 
 func (room *Room) removeCharacter(ch *Character) {
 	room.Characters.Remove(ch)
+	ch.On = nil
 
 	// If the origin room was planar, remove this character from its atlas' character lookup quadtree
 	if room.Flags&ROOM_PLANAR != 0 && room.Plane != nil {
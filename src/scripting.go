@@ -436,6 +436,13 @@ func (game *Game) InvokeNamedEventHandlersWithContextAndArguments(name string, t
 	return nil, nil
 }
 
+/* Whether any script handles an event, so callers can skip building its arguments */
+func (game *Game) hasEventHandlers(name string) bool {
+	handlers, ok := game.eventHandlers[name]
+
+	return ok && handlers != nil && handlers.Count > 0
+}
+
 func (game *Game) LoadScripts() error {
 	const ScriptDirectory = "scripts"
 
@@ -565,9 +572,26 @@ func (game *Game) InitScripting() error {
 		}
 
 		CommandTable[command] = scriptedCommand
+		rebuildCommandOrder()
+
 		return game.vm.ToValue(scriptedCommand)
 	}))
 
+	obj.Set("registerChannel", game.vm.ToValue(func(definition goja.Value) goja.Value {
+		if definition == nil || goja.IsUndefined(definition) || goja.IsNull(definition) {
+			return goja.Null()
+		}
+
+		channel, err := game.channelFromScript(definition.ToObject(game.vm))
+		if err != nil {
+			log.Printf("Script tried to register an invalid channel: %v\r\n", err)
+			return goja.Null()
+		}
+
+		game.registerChannel(channel)
+		return game.vm.ToValue(channel)
+	}))
+
 	knownLocationsConstantsObj := game.vm.NewObject()
 	knownLocationsConstantsObj.Set("Limbo", RoomLimbo)
 	knownLocationsConstantsObj.Set("DeveloperLounge", RoomDeveloperLounge)
@@ -664,6 +688,24 @@ func (game *Game) InitScripting() error {
 	combatObj.Set("DamageTypeSlash", game.vm.ToValue(DamageTypeSlash))
 	combatObj.Set("DamageTypeStab", game.vm.ToValue(DamageTypeStab))
 	combatObj.Set("DamageTypeExotic", game.vm.ToValue(DamageTypeExotic))
+	combatObj.Set("DamageTypeFire", game.vm.ToValue(DamageTypeFire))
+	combatObj.Set("DamageTypeCold", game.vm.ToValue(DamageTypeCold))
+	combatObj.Set("DamageTypeShock", game.vm.ToValue(DamageTypeShock))
+	combatObj.Set("DamageTypePoison", game.vm.ToValue(DamageTypePoison))
+	combatObj.Set("DamageTypeAcid", game.vm.ToValue(DamageTypeAcid))
+	combatObj.Set("DamageTypeHoly", game.vm.ToValue(DamageTypeHoly))
+	combatObj.Set("DamageTypeNegative", game.vm.ToValue(DamageTypeNegative))
+	combatObj.Set("ImmunityNormal", game.vm.ToValue(ImmunityNormal))
+	combatObj.Set("ImmunityResistant", game.vm.ToValue(ImmunityResistant))
+	combatObj.Set("ImmunitySusceptible", game.vm.ToValue(ImmunitySusceptible))
+	combatObj.Set("ImmunityImmune", game.vm.ToValue(ImmunityImmune))
+	combatObj.Set("HitResultMiss", game.vm.ToValue(HitResultMiss))
+	combatObj.Set("HitResultDodge", game.vm.ToValue(HitResultDodge))
+	combatObj.Set("HitResultTumble", game.vm.ToValue(HitResultTumble))
+	combatObj.Set("HitResultParry", game.vm.ToValue(HitResultParry))
+	combatObj.Set("HitResultBlock", game.vm.ToValue(HitResultBlock))
+	combatObj.Set("HitResultHit", game.vm.ToValue(HitResultHit))
+	combatObj.Set("HitResultCritical", game.vm.ToValue(HitResultCritical))
 
 	httpUtilityObj := game.vm.NewObject()
 	httpUtilityObj.Set("Get", game.vm.ToValue(SimpleGET))
@@ -702,6 +744,7 @@ func (game *Game) InitScripting() error {
 	utilObj.Set("findObjectFlag", game.vm.ToValue(FindObjectFlag))
 	utilObj.Set("findExitFlag", game.vm.ToValue(FindExitFlag))
 	utilObj.Set("findRoomFlag", game.vm.ToValue(FindRoomFlag))
+	utilObj.Set("findDamageTypeFlag", game.vm.ToValue(FindDamageTypeFlag))
 
 	levelConstantsObj := game.vm.NewObject()
 	levelConstantsObj.Set("LevelAdmin", LevelAdmin)
@@ -724,8 +767,41 @@ func (game *Game) InitScripting() error {
 	obj.Set("HTTP", httpUtilityObj)
 	obj.Set("NewExit", game.vm.ToValue(game.NewExit))
 	obj.Set("Levels", levelConstantsObj)
+	obj.Set("PulsesPerSecond", PulsesPerSecond)
+
+	gmcpObj := game.vm.NewObject()
+	gmcpObj.Set("send", game.vm.ToValue(func(ch *Character, pkg goja.Value, data goja.Value) goja.Value {
+		if ch == nil || ch.Client == nil {
+			return game.vm.ToValue(false)
+		}
+
+		var payload interface{} = nil
+		if data != nil && !goja.IsUndefined(data) && !goja.IsNull(data) {
+			payload = data.Export()
+		}
+
+		sent, err := ch.Client.sendGMCP(pkg.String(), payload)
+		if err != nil {
+			log.Printf("Script failed to send GMCP %s: %v\r\n", pkg.String(), err)
+			return game.vm.ToValue(false)
+		}
+
+		return game.vm.ToValue(sent)
+	}))
+
+	rateLimitObj := game.vm.NewObject()
+	rateLimitObj.Set("exempt", game.vm.ToValue(func(ch *Character, exempt bool) goja.Value {
+		if ch == nil || ch.Client == nil {
+			return game.vm.ToValue(false)
+		}
+
+		ch.Client.setRateLimitExempt(exempt)
+		return game.vm.ToValue(true)
+	}))
 
 	obj.Set("util", utilObj)
+	obj.Set("gmcp", gmcpObj)
+	obj.Set("rateLimit", rateLimitObj)
 
 	sentryObj := game.vm.NewObject()
 	sentryObj.Set("captureMessage", game.vm.ToValue(sentry.CaptureMessage))
@@ -252,6 +252,7 @@ func do_buy(ch *Character, arguments string) {
 			ch.AddObject(obj)
 			ch.Game.Objects.Insert(obj)
 			ch.Gold -= listing.Price
+			ch.markDirty()
 			return
 		}
 
@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+/* Environment variable through which a copyover passes its manifest to the new process */
+const CopyoverManifestEnvironmentVariable = "GOLEM_COPYOVER_MANIFEST"
+
+/* How long to wait for a client's pending output to reach the socket when going down */
+const ShutdownDrainTimeout = 2 * time.Second
+
+type CopyoverClient struct {
+	FD               uintptr                    `json:"fd"`
+	Name             string                     `json:"name"`
+	AccountId        int                        `json:"accountId"`
+	SessionStartedAt time.Time                  `json:"sessionStartedAt"`
+	AnsiEnabled      bool                       `json:"ansiEnabled"`
+	TerminalType     string                     `json:"terminalType"`
+	WindowWidth      int                        `json:"windowWidth"`
+	WindowHeight     int                        `json:"windowHeight"`
+	TelnetOptions    map[byte]TelnetOptionState `json:"telnetOptions"`
+}
+
+type CopyoverManifest struct {
+	ListenerFD uintptr          `json:"listenerFd"`
+	Clients    []CopyoverClient `json:"clients"`
+}
+
+/* Save every player and any plane whose terrain changed */
+func (game *Game) saveWorld() {
+	for iter := game.Characters.Head; iter != nil; iter = iter.Next {
+		ch := iter.Value.(*Character)
+
+		if ch.Flags&CHAR_IS_PLAYER == 0 {
+			continue
+		}
+
+		if !ch.Save() {
+			log.Printf("Failed to save player %s.\r\n", ch.Name)
+		}
+	}
+
+	game.savePlanes()
+}
+
+/* Flush a client's paged output and wait for the write pump to put it on the wire */
+func (client *Client) drainOutput() {
+	if client.Character != nil {
+		client.Character.flushOutput()
+	}
+
+	client.stopWriting(ShutdownDrainTimeout)
+}
+
+func (game *Game) shutdown() {
+	log.Printf("Shutting down.\r\n")
+
+	game.broadcast("{RThe world fades away as the server shuts down.{x\r\n", nil)
+	game.saveWorld()
+
+	for client := range game.clients {
+		client.drainOutput()
+		client.conn.Close()
+	}
+
+	os.Exit(0)
+}
+
+/* Duplicate a socket's file descriptor so that it survives exec */
+func inheritableFile(conn interface{ File() (*os.File, error) }) (*os.File, error) {
+	file, err := conn.File()
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_SETFD, 0)
+	if errno != 0 {
+		file.Close()
+		return nil, errno
+	}
+
+	return file, nil
+}
+
+/*
+ * Replace the running binary with a fresh copy of itself without dropping players.
+ * The listening socket and every in-game telnet session are handed down as open file
+ * descriptors, described by a JSON manifest the new process picks up in main.  Clients
+ * which can't be carried over (TLS, WebSocket, or still logging in) are asked to reconnect.
+ */
+func (game *Game) copyover() error {
+	listener, ok := game.listener.(*net.TCPListener)
+	if !ok {
+		return errors.New("listening socket is not a TCP listener")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	listenerFile, err := inheritableFile(listener)
+	if err != nil {
+		return err
+	}
+
+	manifestFile, err := ioutil.TempFile("", "golem-copyover-*.json")
+	if err != nil {
+		return err
+	}
+
+	manifest := &CopyoverManifest{
+		ListenerFD: listenerFile.Fd(),
+		Clients:    make([]CopyoverClient, 0),
+	}
+
+	game.broadcast("{YThe world shimmers as the server reboots around you; please wait...{x\r\n", nil)
+	game.saveWorld()
+
+	files := []*os.File{listenerFile}
+
+	for client := range game.clients {
+		conn, ok := client.conn.(*net.TCPConn)
+		if !ok || client.ConnectionState != ConnectionStatePlaying || client.Character == nil {
+			client.Send([]byte("\r\nThe server is rebooting; please reconnect in a moment.\r\n"))
+			client.drainOutput()
+			client.conn.Close()
+			continue
+		}
+
+		file, err := inheritableFile(conn)
+		if err != nil {
+			log.Printf("Unable to carry %s over: %v\r\n", client.Character.Name, err)
+			client.Character.Send("\r\nThe server is rebooting; please reconnect in a moment.\r\n")
+			client.drainOutput()
+			client.conn.Close()
+			continue
+		}
+
+		files = append(files, file)
+
+		/* Stop the write pump first so any compression stream is finished */
+		client.drainOutput()
+
+		entry := CopyoverClient{
+			FD:               file.Fd(),
+			Name:             client.Character.Name,
+			AccountId:        client.Character.AccountId,
+			SessionStartedAt: client.sessionStartedAt,
+			AnsiEnabled:      client.ansiEnabled,
+			TelnetOptions:    make(map[byte]TelnetOptionState),
+		}
+
+		client.telnetMutex.Lock()
+		entry.TerminalType = client.terminalType
+		entry.WindowWidth = client.windowWidth
+		entry.WindowHeight = client.windowHeight
+
+		for option, state := range client.telnetOptions {
+			entry.TelnetOptions[option] = *state
+		}
+		client.telnetMutex.Unlock()
+
+		manifest.Clients = append(manifest.Clients, entry)
+	}
+
+	/* Every write pump has stopped by now, so there is no going back on failure */
+	encoded, err := json.Marshal(manifest)
+	if err == nil {
+		_, err = manifestFile.Write(encoded)
+	}
+
+	manifestFile.Close()
+
+	if err == nil {
+		log.Printf("Copyover: executing %s with %d connected players.\r\n", executable, len(manifest.Clients))
+
+		env := append(os.Environ(), fmt.Sprintf("%s=%s", CopyoverManifestEnvironmentVariable, manifestFile.Name()))
+		err = syscall.Exec(executable, os.Args, env)
+	}
+
+	log.Printf("Copyover failed after sessions were detached, exiting: %v\r\n", err)
+	os.Remove(manifestFile.Name())
+
+	for _, file := range files {
+		file.Close()
+	}
+
+	os.Exit(1)
+	return err
+}
+
+/* Read the manifest left by a copyover, if this process was started by one */
+func LoadCopyoverManifest() (*CopyoverManifest, error) {
+	path := os.Getenv(CopyoverManifestEnvironmentVariable)
+	if path == "" {
+		return nil, nil
+	}
+
+	os.Unsetenv(CopyoverManifestEnvironmentVariable)
+	defer os.Remove(path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &CopyoverManifest{}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (manifest *CopyoverManifest) Listener() (net.Listener, error) {
+	file := os.NewFile(manifest.ListenerFD, "listener")
+	defer file.Close()
+
+	return net.FileListener(file)
+}
+
+/* Reattach every session carried over; must run before the game loop starts */
+func (game *Game) recoverCopyover(manifest *CopyoverManifest) {
+	for _, entry := range manifest.Clients {
+		file := os.NewFile(entry.FD, entry.Name)
+		conn, err := net.FileConn(file)
+		file.Close()
+
+		if err != nil {
+			log.Printf("Copyover: unable to recover connection for %s: %v\r\n", entry.Name, err)
+			continue
+		}
+
+		ch, room, err := game.FindPlayerByName(entry.Name)
+		if err != nil || ch == nil {
+			log.Printf("Copyover: unable to reload player %s: %v\r\n", entry.Name, err)
+			conn.Write([]byte("\r\nYour character could not be restored after the reboot; please reconnect.\r\n"))
+			conn.Close()
+			continue
+		}
+
+		client := NewClient(conn)
+		client.sessionStartedAt = entry.SessionStartedAt
+		client.ansiEnabled = entry.AnsiEnabled
+		client.terminalType = entry.TerminalType
+		client.windowWidth = entry.WindowWidth
+		client.windowHeight = entry.WindowHeight
+
+		for option, state := range entry.TelnetOptions {
+			restored := state
+			client.telnetOptions[option] = &restored
+		}
+
+		go client.readPump(game)
+		go client.writePump(game)
+
+		/* The old process finished its compression stream; start a new one */
+		if client.isLocalOptionEnabled(TelnetMCCP2) {
+			client.startCompression()
+		}
+
+		ch.Client = client
+		ch.Room = room
+		ch.Flags |= CHAR_IS_PLAYER
+
+		/* Account commands and the character menu need the account the player logged in to */
+		accountId := entry.AccountId
+		if accountId == 0 {
+			accountId = ch.AccountId
+		}
+
+		account, err := game.FindAccountByID(accountId)
+		if err != nil || account == nil {
+			log.Printf("Copyover: unable to restore account for %s: %v\r\n", entry.Name, err)
+		}
+
+		client.account = account
+		client.Character = ch
+		client.ConnectionState = ConnectionStatePlaying
+
+		game.clients[client] = true
+		game.enterWorld(ch)
+
+		ch.Send("{YThe world comes back into focus; the reboot is complete.{x\r\n")
+		do_look(ch, "")
+	}
+
+	log.Printf("Copyover: recovered %d of %d players.\r\n", len(game.clients), len(manifest.Clients))
+}
@@ -233,6 +233,7 @@ func do_practice(ch *Character, arguments string) {
 
 		ch.Practices -= prof.Complexity
 		prof.Proficiency++
+		ch.markDirty()
 		ch.Send(fmt.Sprintf("{WYou practice %s!{x\r\n", skill.Name))
 		return
 	}
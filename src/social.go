@@ -0,0 +1,306 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+const MaxSocialNameLength = 32
+
+/*
+ * A social is a bit of in-room flavour like grin or nod, with a message for each way it
+ * can be used.  Within a message, $n names the actor and $N the target; any message left
+ * empty is simply not shown.
+ */
+type Social struct {
+	Game *Game `json:"-"`
+
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+
+	CharNoArg   string `json:"charNoArg"`
+	OthersNoArg string `json:"othersNoArg"`
+	CharFound   string `json:"charFound"`
+	OthersFound string `json:"othersFound"`
+	VictFound   string `json:"victFound"`
+	CharAuto    string `json:"charAuto"`
+	OthersAuto  string `json:"othersAuto"`
+	NotFound    string `json:"notFound"`
+}
+
+func (game *Game) LoadSocials() error {
+	log.Printf("Loading socials.\r\n")
+
+	game.socials = make(map[string]*Social)
+
+	rows, err := game.db.Query(`
+		SELECT
+			id,
+			name,
+			char_no_arg,
+			others_no_arg,
+			char_found,
+			others_found,
+			vict_found,
+			char_auto,
+			others_auto,
+			not_found
+		FROM
+			socials
+		WHERE
+			deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var messages [8]sql.NullString
+
+		social := &Social{Game: game}
+
+		err := rows.Scan(&social.Id, &social.Name, &messages[0], &messages[1], &messages[2], &messages[3], &messages[4], &messages[5], &messages[6], &messages[7])
+		if err != nil {
+			log.Printf("Unable to scan social: %v.\r\n", err)
+			return err
+		}
+
+		social.CharNoArg = messages[0].String
+		social.OthersNoArg = messages[1].String
+		social.CharFound = messages[2].String
+		social.OthersFound = messages[3].String
+		social.VictFound = messages[4].String
+		social.CharAuto = messages[5].String
+		social.OthersAuto = messages[6].String
+		social.NotFound = messages[7].String
+
+		game.socials[strings.ToLower(social.Name)] = social
+	}
+
+	log.Printf("Loaded %d socials.\r\n", len(game.socials))
+	return nil
+}
+
+func (game *Game) FindSocial(name string) *Social {
+	return game.socials[strings.ToLower(name)]
+}
+
+/* The alphabetically first social starting with prefix */
+func (game *Game) findSocialByPrefix(prefix string) *Social {
+	var found *Social = nil
+
+	for name, social := range game.socials {
+		if strings.HasPrefix(name, prefix) && (found == nil || name < strings.ToLower(found.Name)) {
+			found = social
+		}
+	}
+
+	return found
+}
+
+/* Create an empty social, ready to have its messages filled in by sedit */
+func (game *Game) CreateSocial(name string) (*Social, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || len(name) > MaxSocialNameLength || strings.ContainsAny(name, " \t") {
+		return nil, errors.New("invalid social name")
+	}
+
+	if _, ok := CommandTable[name]; ok {
+		return nil, errors.New("a command by that name already exists")
+	}
+
+	if game.FindSocial(name) != nil {
+		return nil, errors.New("a social by that name already exists")
+	}
+
+	result, err := game.db.Exec(`
+		INSERT INTO
+			socials(name)
+		VALUES
+			(?)
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	social := &Social{Game: game, Id: int(id), Name: name}
+	game.socials[name] = social
+	return social, nil
+}
+
+func (game *Game) DeleteSocial(social *Social) error {
+	_, err := game.db.Exec(`
+		UPDATE
+			socials
+		SET
+			deleted_at = NOW()
+		WHERE
+			id = ?
+	`, social.Id)
+	if err != nil {
+		return err
+	}
+
+	delete(game.socials, strings.ToLower(social.Name))
+	return nil
+}
+
+func (social *Social) Sync() error {
+	_, err := social.Game.db.Exec(`
+		UPDATE
+			socials
+		SET
+			char_no_arg = ?,
+			others_no_arg = ?,
+			char_found = ?,
+			others_found = ?,
+			vict_found = ?,
+			char_auto = ?,
+			others_auto = ?,
+			not_found = ?
+		WHERE
+			id = ?
+	`, social.CharNoArg, social.OthersNoArg, social.CharFound, social.OthersFound, social.VictFound, social.CharAuto, social.OthersAuto, social.NotFound, social.Id)
+
+	return err
+}
+
+/* Fill in a social message as seen by viewer */
+func socialMessage(format string, ch *Character, victim *Character, viewer *Character) string {
+	var buf strings.Builder
+
+	for index := 0; index < len(format); index++ {
+		if format[index] != '$' || index+1 >= len(format) {
+			buf.WriteByte(format[index])
+			continue
+		}
+
+		index++
+
+		switch format[index] {
+		case 'n':
+			buf.WriteString(ch.GetShortDescription(viewer))
+
+		case 'N':
+			if victim != nil {
+				buf.WriteString(victim.GetShortDescription(viewer))
+			}
+
+		default:
+			buf.WriteByte('$')
+			buf.WriteByte(format[index])
+		}
+	}
+
+	message := buf.String()
+	if message == "" {
+		return ""
+	}
+
+	/* Capitalize the first letter, as $n often starts the sentence */
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+func (ch *Character) performSocial(social *Social, arguments string) {
+	var victim *Character = nil
+	var toActor, toVictim, toOthers string
+
+	if ch.Position < PositionResting {
+		ch.Send(positionRefusal(ch.Position))
+		return
+	}
+
+	argument, _ := OneArgument(arguments)
+
+	if argument == "" {
+		toActor = social.CharNoArg
+		toOthers = social.OthersNoArg
+	} else {
+		victim = ch.FindCharacterInRoom(argument)
+
+		if victim == nil {
+			ch.Send(fmt.Sprintf("%s\r\n", socialMessage(social.NotFound, ch, nil, ch)))
+			return
+		}
+
+		if victim == ch {
+			toActor = social.CharAuto
+			toOthers = social.OthersAuto
+		} else {
+			toActor = social.CharFound
+			toVictim = social.VictFound
+			toOthers = social.OthersFound
+		}
+	}
+
+	if toActor != "" {
+		ch.Send(fmt.Sprintf("%s\r\n", socialMessage(toActor, ch, victim, ch)))
+	}
+
+	if ch.Room == nil {
+		return
+	}
+
+	for iter := ch.Room.Characters.Head; iter != nil; iter = iter.Next {
+		rch := iter.Value.(*Character)
+
+		if rch == ch {
+			continue
+		}
+
+		if rch == victim {
+			if toVictim != "" {
+				rch.Send(fmt.Sprintf("\r\n%s\r\n", socialMessage(toVictim, ch, victim, rch)))
+			}
+
+			continue
+		}
+
+		if toOthers != "" {
+			rch.Send(fmt.Sprintf("\r\n%s\r\n", socialMessage(toOthers, ch, victim, rch)))
+		}
+	}
+}
+
+func do_socials(ch *Character, arguments string) {
+	var buf strings.Builder
+
+	names := make([]string, 0, len(ch.Game.socials))
+	for name := range ch.Game.socials {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for index, name := range names {
+		buf.WriteString(fmt.Sprintf("%-12s", name))
+
+		if index%6 == 5 {
+			buf.WriteString("\r\n")
+		}
+	}
+
+	if len(names)%6 != 0 {
+		buf.WriteString("\r\n")
+	}
+
+	ch.Send(buf.String())
+}
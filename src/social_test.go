@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+type socialMessageTest struct {
+	format, expected string
+}
+
+var socialMessageTests = []socialMessageTest{
+	{"$n grins evilly at $N.", "Alice grins evilly at the town guard."},
+	{"$N is grinned at.", "The town guard is grinned at."},
+	{"You grin.", "You grin."},
+	{"Costs $5 or $$.", "Costs $5 or $$."},
+	{"Trailing $", "Trailing $"},
+	{"", ""},
+}
+
+func TestSocialMessage(t *testing.T) {
+	ch := NewCharacter()
+	ch.Name = "Alice"
+	ch.Flags = CHAR_IS_PLAYER
+
+	victim := NewCharacter()
+	victim.ShortDescription = "the town guard"
+
+	for _, test := range socialMessageTests {
+		result := socialMessage(test.format, ch, victim, ch)
+		if result != test.expected {
+			t.Errorf("socialMessage(%q) = %q, expected %q", test.format, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,595 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+/*
+ * Telnet references used:
+ *     http://pcmicro.com/netfoss/telnet.html
+ *     RFC 854 (Telnet protocol specification)
+ *     RFC 1073 (Telnet window size option)
+ *     RFC 1143 (The Q method of implementing telnet option negotiation)
+ */
+
+/* OOB telnet messaging */
+const (
+	TelnetECHO                 = 1
+	TelnetSUPPRESSGOAHEAD      = 3
+	TelnetTERMINALTYPE         = 24
+	TelnetWINDOWSIZE           = 31
+	TelnetTS                   = 32
+	TelnetENVIRONMENTVARIABLES = 36
+	TelnetNEWENVIRONMENT       = 39
+	TelnetMCCP2                = 86
+	TelnetGMCP                 = 201
+
+	TelnetSE        = 240
+	TelnetNOP       = 241
+	TelnetERASELINE = 248
+	TelnetGA        = 249
+	TelnetSB        = 250
+	TelnetWILL      = 251
+	TelnetWONT      = 252
+	TelnetDO        = 253
+	TelnetDONT      = 254
+	TelnetIAC       = 255
+)
+
+/* Subnegotiation qualifiers for TTYPE and similar options */
+const (
+	TelnetQualifierIS   = 0
+	TelnetQualifierSEND = 1
+)
+
+/* Parser states while walking the inbound byte stream */
+const (
+	TelnetParserStateData         = 0
+	TelnetParserStateIAC          = 1
+	TelnetParserStateNegotiation  = 2
+	TelnetParserStateSubnegotiate = 3
+	TelnetParserStateSubnegIAC    = 4
+	TelnetParserStateCarriage     = 5
+)
+
+/* Q method option states, per side of the connection */
+const (
+	TelnetOptionNo      = 0
+	TelnetOptionYes     = 1
+	TelnetOptionWantNo  = 2
+	TelnetOptionWantYes = 3
+)
+
+/* Q method queue bit: empty or opposite */
+const (
+	TelnetQueueEmpty    = 0
+	TelnetQueueOpposite = 1
+)
+
+const TelnetMaxLineLength = 512
+const TelnetMaxSubnegotiationLength = 8192
+
+var ErrTelnetLineTooLong = errors.New("telnet line exceeded maximum length")
+
+/*
+ * Negotiation state for a single option on a single client.  "Us" tracks whether
+ * the server side has the option enabled (WILL/WONT from us, DO/DONT from them),
+ * "him" tracks the client side (WILL/WONT from them, DO/DONT from us).
+ */
+type TelnetOptionState struct {
+	Us      int
+	UsQueue int
+
+	Him      int
+	HimQueue int
+}
+
+/*
+ * Options the server is prepared to negotiate.  Local indicates that the server
+ * may enable the option on its own side, Remote that the client may enable it.
+ * Anything absent from this table is refused.
+ */
+type TelnetOption struct {
+	Name   string
+	Local  bool
+	Remote bool
+
+	OnEnabled        func(client *Client, local bool)
+	OnDisabled       func(client *Client, local bool)
+	OnSubnegotiation func(client *Client, data []byte)
+}
+
+var TelnetOptionTable map[byte]*TelnetOption = make(map[byte]*TelnetOption)
+
+func init() {
+	TelnetOptionTable[TelnetECHO] = &TelnetOption{Name: "ECHO", Local: true}
+	TelnetOptionTable[TelnetSUPPRESSGOAHEAD] = &TelnetOption{Name: "SGA", Local: true, Remote: true}
+	TelnetOptionTable[TelnetTERMINALTYPE] = &TelnetOption{
+		Name:   "TTYPE",
+		Remote: true,
+		OnEnabled: func(client *Client, local bool) {
+			client.sendSubnegotiation(TelnetTERMINALTYPE, []byte{TelnetQualifierSEND})
+		},
+		OnSubnegotiation: func(client *Client, data []byte) {
+			if len(data) < 1 || data[0] != TelnetQualifierIS {
+				return
+			}
+
+			client.telnetMutex.Lock()
+			client.terminalType = string(data[1:])
+			client.telnetMutex.Unlock()
+		},
+	}
+	TelnetOptionTable[TelnetWINDOWSIZE] = &TelnetOption{
+		Name:   "NAWS",
+		Remote: true,
+		OnDisabled: func(client *Client, local bool) {
+			client.telnetMutex.Lock()
+			client.windowWidth = 0
+			client.windowHeight = 0
+			client.telnetMutex.Unlock()
+		},
+		OnSubnegotiation: func(client *Client, data []byte) {
+			/* RFC 1073: IAC SB NAWS <width16> <height16> IAC SE, where zero means unknown */
+			if len(data) != 4 {
+				return
+			}
+
+			client.telnetMutex.Lock()
+			client.windowWidth = int(data[0])<<8 | int(data[1])
+			client.windowHeight = int(data[2])<<8 | int(data[3])
+			client.telnetMutex.Unlock()
+		},
+	}
+}
+
+/*
+ * TelnetParser is a byte-at-a-time state machine separating line input from
+ * inline IAC commands and subnegotiations.  Escaped IAC IAC sequences produce
+ * a literal 255 data byte, and bare or NUL-padded carriage returns end a line.
+ */
+type TelnetParser struct {
+	state          int
+	command        byte
+	option         byte
+	line           []byte
+	subnegotiation []byte
+
+	OnLine           func(line []byte)
+	OnNegotiation    func(command byte, option byte)
+	OnSubnegotiation func(option byte, data []byte)
+	OnCommand        func(command byte)
+}
+
+func NewTelnetParser() *TelnetParser {
+	return &TelnetParser{
+		state:          TelnetParserStateData,
+		line:           make([]byte, 0, 128),
+		subnegotiation: make([]byte, 0, 64),
+	}
+}
+
+func (parser *TelnetParser) emitLine() {
+	line := make([]byte, len(parser.line))
+	copy(line, parser.line)
+	parser.line = parser.line[:0]
+
+	if parser.OnLine != nil {
+		parser.OnLine(line)
+	}
+}
+
+func (parser *TelnetParser) Parse(data []byte) error {
+	for _, b := range data {
+		switch parser.state {
+		case TelnetParserStateCarriage:
+			/* CR LF and CR NUL were already handled by the CR itself */
+			parser.state = TelnetParserStateData
+			if b == '\n' || b == 0 {
+				continue
+			}
+
+			fallthrough
+
+		case TelnetParserStateData:
+			switch b {
+			case TelnetIAC:
+				parser.state = TelnetParserStateIAC
+			case '\r':
+				parser.state = TelnetParserStateCarriage
+				parser.emitLine()
+			case '\n':
+				parser.emitLine()
+			case 0:
+				/* Stray NULs carry no meaning in line mode */
+			default:
+				if len(parser.line) >= TelnetMaxLineLength {
+					return ErrTelnetLineTooLong
+				}
+
+				parser.line = append(parser.line, b)
+			}
+
+		case TelnetParserStateIAC:
+			switch b {
+			case TelnetIAC:
+				/* Escaped 255 data byte */
+				parser.state = TelnetParserStateData
+				if len(parser.line) >= TelnetMaxLineLength {
+					return ErrTelnetLineTooLong
+				}
+
+				parser.line = append(parser.line, TelnetIAC)
+			case TelnetWILL, TelnetWONT, TelnetDO, TelnetDONT:
+				parser.command = b
+				parser.state = TelnetParserStateNegotiation
+			case TelnetSB:
+				parser.subnegotiation = parser.subnegotiation[:0]
+				parser.state = TelnetParserStateNegotiation
+				parser.command = b
+			default:
+				parser.state = TelnetParserStateData
+				if parser.OnCommand != nil {
+					parser.OnCommand(b)
+				}
+			}
+
+		case TelnetParserStateNegotiation:
+			if parser.command == TelnetSB {
+				parser.option = b
+				parser.state = TelnetParserStateSubnegotiate
+				continue
+			}
+
+			parser.state = TelnetParserStateData
+			if parser.OnNegotiation != nil {
+				parser.OnNegotiation(parser.command, b)
+			}
+
+		case TelnetParserStateSubnegotiate:
+			if b == TelnetIAC {
+				parser.state = TelnetParserStateSubnegIAC
+				continue
+			}
+
+			if len(parser.subnegotiation) < TelnetMaxSubnegotiationLength {
+				parser.subnegotiation = append(parser.subnegotiation, b)
+			}
+
+		case TelnetParserStateSubnegIAC:
+			switch b {
+			case TelnetSE:
+				parser.state = TelnetParserStateData
+
+				data := make([]byte, len(parser.subnegotiation))
+				copy(data, parser.subnegotiation)
+
+				if parser.OnSubnegotiation != nil {
+					parser.OnSubnegotiation(parser.option, data)
+				}
+			case TelnetIAC:
+				parser.state = TelnetParserStateSubnegotiate
+				if len(parser.subnegotiation) < TelnetMaxSubnegotiationLength {
+					parser.subnegotiation = append(parser.subnegotiation, TelnetIAC)
+				}
+			default:
+				/* Malformed: treat as the end of the subnegotiation and process the command */
+				parser.state = TelnetParserStateIAC
+				if err := parser.Parse([]byte{b}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/* Result of a state transition that the caller needs to act upon outside of the lock */
+type telnetTransition struct {
+	response []byte
+	option   byte
+	local    bool
+	enabled  bool
+	changed  bool
+}
+
+func (client *Client) telnetOption(option byte) *TelnetOptionState {
+	state, ok := client.telnetOptions[option]
+	if !ok {
+		state = &TelnetOptionState{}
+		client.telnetOptions[option] = state
+	}
+
+	return state
+}
+
+/* Handle an inbound WILL/WONT/DO/DONT per the RFC 1143 Q method. */
+func (client *Client) handleNegotiation(command byte, option byte) {
+	var transition telnetTransition = telnetTransition{option: option}
+
+	supported, ok := TelnetOptionTable[option]
+
+	client.telnetMutex.Lock()
+	state := client.telnetOption(option)
+
+	switch command {
+	case TelnetWILL:
+		transition.local = false
+
+		switch state.Him {
+		case TelnetOptionNo:
+			if ok && supported.Remote {
+				state.Him = TelnetOptionYes
+				transition.response = []byte{TelnetIAC, TelnetDO, option}
+				transition.enabled, transition.changed = true, true
+			} else {
+				transition.response = []byte{TelnetIAC, TelnetDONT, option}
+			}
+		case TelnetOptionWantNo:
+			if state.HimQueue == TelnetQueueEmpty {
+				/* Client answered DONT with WILL; RFC 1143 calls this an error */
+				state.Him = TelnetOptionNo
+			} else {
+				state.Him = TelnetOptionYes
+				state.HimQueue = TelnetQueueEmpty
+				transition.enabled, transition.changed = true, true
+			}
+		case TelnetOptionWantYes:
+			if state.HimQueue == TelnetQueueEmpty {
+				state.Him = TelnetOptionYes
+				transition.enabled, transition.changed = true, true
+			} else {
+				state.Him = TelnetOptionWantNo
+				state.HimQueue = TelnetQueueEmpty
+				transition.response = []byte{TelnetIAC, TelnetDONT, option}
+			}
+		}
+
+	case TelnetWONT:
+		transition.local = false
+
+		switch state.Him {
+		case TelnetOptionYes:
+			state.Him = TelnetOptionNo
+			transition.response = []byte{TelnetIAC, TelnetDONT, option}
+			transition.changed = true
+		case TelnetOptionWantNo:
+			if state.HimQueue == TelnetQueueEmpty {
+				state.Him = TelnetOptionNo
+				transition.changed = true
+			} else {
+				state.Him = TelnetOptionWantYes
+				state.HimQueue = TelnetQueueEmpty
+				transition.response = []byte{TelnetIAC, TelnetDO, option}
+			}
+		case TelnetOptionWantYes:
+			state.Him = TelnetOptionNo
+			state.HimQueue = TelnetQueueEmpty
+		}
+
+	case TelnetDO:
+		transition.local = true
+
+		switch state.Us {
+		case TelnetOptionNo:
+			if ok && supported.Local {
+				state.Us = TelnetOptionYes
+				transition.response = []byte{TelnetIAC, TelnetWILL, option}
+				transition.enabled, transition.changed = true, true
+			} else {
+				transition.response = []byte{TelnetIAC, TelnetWONT, option}
+			}
+		case TelnetOptionWantNo:
+			if state.UsQueue == TelnetQueueEmpty {
+				state.Us = TelnetOptionNo
+			} else {
+				state.Us = TelnetOptionYes
+				state.UsQueue = TelnetQueueEmpty
+				transition.enabled, transition.changed = true, true
+			}
+		case TelnetOptionWantYes:
+			if state.UsQueue == TelnetQueueEmpty {
+				state.Us = TelnetOptionYes
+				transition.enabled, transition.changed = true, true
+			} else {
+				state.Us = TelnetOptionWantNo
+				state.UsQueue = TelnetQueueEmpty
+				transition.response = []byte{TelnetIAC, TelnetWONT, option}
+			}
+		}
+
+	case TelnetDONT:
+		transition.local = true
+
+		switch state.Us {
+		case TelnetOptionYes:
+			state.Us = TelnetOptionNo
+			transition.response = []byte{TelnetIAC, TelnetWONT, option}
+			transition.changed = true
+		case TelnetOptionWantNo:
+			if state.UsQueue == TelnetQueueEmpty {
+				state.Us = TelnetOptionNo
+				transition.changed = true
+			} else {
+				state.Us = TelnetOptionWantYes
+				state.UsQueue = TelnetQueueEmpty
+				transition.response = []byte{TelnetIAC, TelnetWILL, option}
+			}
+		case TelnetOptionWantYes:
+			state.Us = TelnetOptionNo
+			state.UsQueue = TelnetQueueEmpty
+		}
+	}
+
+	client.telnetMutex.Unlock()
+
+	client.applyTelnetTransition(transition)
+}
+
+func (client *Client) applyTelnetTransition(transition telnetTransition) {
+	if len(transition.response) > 0 {
+		client.Send(transition.response)
+	}
+
+	if !transition.changed {
+		return
+	}
+
+	supported, ok := TelnetOptionTable[transition.option]
+	if !ok {
+		return
+	}
+
+	if transition.enabled && supported.OnEnabled != nil {
+		supported.OnEnabled(client, transition.local)
+	} else if !transition.enabled && supported.OnDisabled != nil {
+		supported.OnDisabled(client, transition.local)
+	}
+}
+
+/* Ask to enable (or disable) an option on the server side: WILL/WONT. */
+func (client *Client) requestLocalOption(option byte, enable bool) {
+	var response []byte
+
+	if client.isWebSocket() {
+		return
+	}
+
+	client.telnetMutex.Lock()
+	state := client.telnetOption(option)
+
+	if enable {
+		switch state.Us {
+		case TelnetOptionNo:
+			state.Us = TelnetOptionWantYes
+			response = []byte{TelnetIAC, TelnetWILL, option}
+		case TelnetOptionWantNo:
+			state.UsQueue = TelnetQueueOpposite
+		case TelnetOptionWantYes:
+			state.UsQueue = TelnetQueueEmpty
+		}
+	} else {
+		switch state.Us {
+		case TelnetOptionYes:
+			state.Us = TelnetOptionWantNo
+			response = []byte{TelnetIAC, TelnetWONT, option}
+		case TelnetOptionWantNo:
+			state.UsQueue = TelnetQueueEmpty
+		case TelnetOptionWantYes:
+			state.UsQueue = TelnetQueueOpposite
+		}
+	}
+
+	client.telnetMutex.Unlock()
+
+	if response != nil {
+		client.Send(response)
+	}
+}
+
+/* Ask the client to enable (or disable) an option on its side: DO/DONT. */
+func (client *Client) requestRemoteOption(option byte, enable bool) {
+	var response []byte
+
+	if client.isWebSocket() {
+		return
+	}
+
+	client.telnetMutex.Lock()
+	state := client.telnetOption(option)
+
+	if enable {
+		switch state.Him {
+		case TelnetOptionNo:
+			state.Him = TelnetOptionWantYes
+			response = []byte{TelnetIAC, TelnetDO, option}
+		case TelnetOptionWantNo:
+			state.HimQueue = TelnetQueueOpposite
+		case TelnetOptionWantYes:
+			state.HimQueue = TelnetQueueEmpty
+		}
+	} else {
+		switch state.Him {
+		case TelnetOptionYes:
+			state.Him = TelnetOptionWantNo
+			response = []byte{TelnetIAC, TelnetDONT, option}
+		case TelnetOptionWantNo:
+			state.HimQueue = TelnetQueueEmpty
+		case TelnetOptionWantYes:
+			state.HimQueue = TelnetQueueOpposite
+		}
+	}
+
+	client.telnetMutex.Unlock()
+
+	if response != nil {
+		client.Send(response)
+	}
+}
+
+func (client *Client) isLocalOptionEnabled(option byte) bool {
+	client.telnetMutex.Lock()
+	defer client.telnetMutex.Unlock()
+
+	return client.telnetOption(option).Us == TelnetOptionYes
+}
+
+func (client *Client) isRemoteOptionEnabled(option byte) bool {
+	client.telnetMutex.Lock()
+	defer client.telnetMutex.Unlock()
+
+	return client.telnetOption(option).Him == TelnetOptionYes
+}
+
+func (client *Client) handleSubnegotiation(option byte, data []byte) {
+	supported, ok := TelnetOptionTable[option]
+	if !ok || supported.OnSubnegotiation == nil {
+		log.Printf("Ignoring subnegotiation for unsupported telnet option %d.\r\n", option)
+		return
+	}
+
+	supported.OnSubnegotiation(client, data)
+}
+
+/* Write IAC SB <option> <data, with IAC doubled> IAC SE */
+func (client *Client) sendSubnegotiation(option byte, data []byte) {
+	buf := make([]byte, 0, len(data)+5)
+	buf = append(buf, TelnetIAC, TelnetSB, option)
+
+	for _, b := range data {
+		if b == TelnetIAC {
+			buf = append(buf, TelnetIAC)
+		}
+
+		buf = append(buf, b)
+	}
+
+	buf = append(buf, TelnetIAC, TelnetSE)
+	client.Send(buf)
+}
+
+/* Offer the options we'd like to have enabled as soon as a client connects */
+func (client *Client) startNegotiation() {
+	client.requestLocalOption(TelnetSUPPRESSGOAHEAD, true)
+	client.requestRemoteOption(TelnetTERMINALTYPE, true)
+	client.requestRemoteOption(TelnetWINDOWSIZE, true)
+	client.requestLocalOption(TelnetGMCP, true)
+	client.requestLocalOption(TelnetMCCP2, true)
+}
+
+/* Last window size reported via NAWS; either dimension may be zero if unknown */
+func (client *Client) windowSize() (int, int) {
+	client.telnetMutex.Lock()
+	defer client.telnetMutex.Unlock()
+
+	return client.windowWidth, client.windowHeight
+}
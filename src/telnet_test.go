@@ -0,0 +1,294 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func newTestTelnetClient() *Client {
+	return &Client{
+		send:          make(chan ClientOutput, 16),
+		telnetOptions: make(map[byte]*TelnetOptionState),
+	}
+}
+
+func drainTestTelnetClient(client *Client) []byte {
+	var output bytes.Buffer
+
+	for {
+		select {
+		case outgoing := <-client.send:
+			output.Write(outgoing.data)
+		default:
+			return output.Bytes()
+		}
+	}
+}
+
+type telnetParserTest struct {
+	input          []byte
+	expectedLines  []string
+	expectedNeg    [][2]byte
+	expectedSubneg []byte
+}
+
+var telnetParserTests = []telnetParserTest{
+	{
+		[]byte("look\r\nsay hi\n"),
+		[]string{"look", "say hi"},
+		nil,
+		nil,
+	},
+	{
+		[]byte("north\r\x00south\r"),
+		[]string{"north", "south"},
+		nil,
+		nil,
+	},
+	{
+		[]byte{'a', TelnetIAC, TelnetIAC, 'b', '\n'},
+		[]string{"a\xffb"},
+		nil,
+		nil,
+	},
+	{
+		[]byte{'l', TelnetIAC, TelnetDO, TelnetSUPPRESSGOAHEAD, 'o', '\n'},
+		[]string{"lo"},
+		[][2]byte{{TelnetDO, TelnetSUPPRESSGOAHEAD}},
+		nil,
+	},
+	{
+		[]byte{TelnetIAC, TelnetSB, TelnetTERMINALTYPE, TelnetQualifierIS, 'x', TelnetIAC, TelnetIAC, TelnetIAC, TelnetSE},
+		nil,
+		nil,
+		[]byte{TelnetQualifierIS, 'x', TelnetIAC},
+	},
+}
+
+func TestTelnetParser(t *testing.T) {
+	for _, test := range telnetParserTests {
+		var lines []string
+		var negotiations [][2]byte
+		var subnegotiation []byte
+
+		parser := NewTelnetParser()
+		parser.OnLine = func(line []byte) {
+			lines = append(lines, string(line))
+		}
+		parser.OnNegotiation = func(command byte, option byte) {
+			negotiations = append(negotiations, [2]byte{command, option})
+		}
+		parser.OnSubnegotiation = func(option byte, data []byte) {
+			subnegotiation = data
+		}
+
+		/* Feed one byte at a time to exercise state carried between reads */
+		for _, b := range test.input {
+			if err := parser.Parse([]byte{b}); err != nil {
+				t.Fatalf("Parse(%q) returned %v", test.input, err)
+			}
+		}
+
+		if len(lines) != len(test.expectedLines) {
+			t.Errorf("Parse(%q) lines = %q, expected %q", test.input, lines, test.expectedLines)
+			continue
+		}
+
+		for i := range lines {
+			if lines[i] != test.expectedLines[i] {
+				t.Errorf("Parse(%q) lines = %q, expected %q", test.input, lines, test.expectedLines)
+			}
+		}
+
+		if len(negotiations) != len(test.expectedNeg) {
+			t.Errorf("Parse(%q) negotiations = %v, expected %v", test.input, negotiations, test.expectedNeg)
+		}
+
+		if !bytes.Equal(subnegotiation, test.expectedSubneg) {
+			t.Errorf("Parse(%q) subnegotiation = %v, expected %v", test.input, subnegotiation, test.expectedSubneg)
+		}
+	}
+}
+
+func TestTelnetParserLineTooLong(t *testing.T) {
+	parser := NewTelnetParser()
+
+	if err := parser.Parse(bytes.Repeat([]byte{'a'}, TelnetMaxLineLength+1)); err != ErrTelnetLineTooLong {
+		t.Errorf("Parse() of an overlong line returned %v, expected %v", err, ErrTelnetLineTooLong)
+	}
+}
+
+func TestTelnetNegotiationRefusesUnsupported(t *testing.T) {
+	client := newTestTelnetClient()
+
+	client.handleNegotiation(TelnetDO, TelnetTS)
+	if output := drainTestTelnetClient(client); !bytes.Equal(output, []byte{TelnetIAC, TelnetWONT, TelnetTS}) {
+		t.Errorf("DO of unsupported option answered with %v", output)
+	}
+
+	client.handleNegotiation(TelnetWILL, TelnetTS)
+	if output := drainTestTelnetClient(client); !bytes.Equal(output, []byte{TelnetIAC, TelnetDONT, TelnetTS}) {
+		t.Errorf("WILL of unsupported option answered with %v", output)
+	}
+}
+
+func TestTelnetNegotiationDoesNotLoop(t *testing.T) {
+	client := newTestTelnetClient()
+
+	/* Our request is acknowledged: no further response is due */
+	client.requestLocalOption(TelnetSUPPRESSGOAHEAD, true)
+	if output := drainTestTelnetClient(client); !bytes.Equal(output, []byte{TelnetIAC, TelnetWILL, TelnetSUPPRESSGOAHEAD}) {
+		t.Errorf("requestLocalOption sent %v", output)
+	}
+
+	client.handleNegotiation(TelnetDO, TelnetSUPPRESSGOAHEAD)
+	if output := drainTestTelnetClient(client); len(output) != 0 {
+		t.Errorf("acknowledging DO produced a response %v", output)
+	}
+
+	if !client.isLocalOptionEnabled(TelnetSUPPRESSGOAHEAD) {
+		t.Errorf("SGA not enabled after DO")
+	}
+
+	/* A repeated DO for an enabled option must be ignored */
+	client.handleNegotiation(TelnetDO, TelnetSUPPRESSGOAHEAD)
+	if output := drainTestTelnetClient(client); len(output) != 0 {
+		t.Errorf("repeated DO produced a response %v", output)
+	}
+
+	client.handleNegotiation(TelnetDONT, TelnetSUPPRESSGOAHEAD)
+	if output := drainTestTelnetClient(client); !bytes.Equal(output, []byte{TelnetIAC, TelnetWONT, TelnetSUPPRESSGOAHEAD}) {
+		t.Errorf("DONT of enabled option answered with %v", output)
+	}
+
+	client.handleNegotiation(TelnetDONT, TelnetSUPPRESSGOAHEAD)
+	if output := drainTestTelnetClient(client); len(output) != 0 {
+		t.Errorf("repeated DONT produced a response %v", output)
+	}
+}
+
+func TestTelnetWindowSize(t *testing.T) {
+	client := newTestTelnetClient()
+
+	client.requestRemoteOption(TelnetWINDOWSIZE, true)
+	client.handleNegotiation(TelnetWILL, TelnetWINDOWSIZE)
+	client.handleSubnegotiation(TelnetWINDOWSIZE, []byte{0, 100, 0, 30})
+
+	if width, height := client.windowSize(); width != 100 || height != 30 {
+		t.Errorf("windowSize() = %d, %d, expected 100, 30", width, height)
+	}
+
+	ch := &Character{Client: client}
+	if ch.pageLength() != 28 {
+		t.Errorf("pageLength() = %d, expected 28", ch.pageLength())
+	}
+
+	client.handleNegotiation(TelnetWONT, TelnetWINDOWSIZE)
+	if ch.screenWidth() != DefaultScreenWidth || ch.pageLength() != DefaultMaxLines {
+		t.Errorf("window size not reset after WONT NAWS")
+	}
+}
+
+func TestTelnetCompression(t *testing.T) {
+	server, remote := net.Pipe()
+	defer server.Close()
+
+	client := newTestTelnetClient()
+	client.conn = server
+
+	received := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(remote)
+		received <- data
+	}()
+
+	client.telnetOption(TelnetMCCP2).Us = TelnetOptionYes
+
+	/* Output which merely looks like the start sequence doesn't switch compression on */
+	if err := client.writeOutput(mccp2StartSequence, false); err != nil || client.compressor != nil {
+		t.Fatalf("expected unmarked output not to start compression (%v)", err)
+	}
+
+	for _, message := range []ClientOutput{{data: mccp2StartSequence, startCompression: true}, {data: []byte("compressed\r\n")}} {
+		if err := client.writeOutput(message.data, message.startCompression); err != nil {
+			t.Fatalf("writeOutput() returned %v", err)
+		}
+	}
+
+	client.endCompression()
+	server.Close()
+
+	data := <-received
+	if !bytes.HasPrefix(data, append(append([]byte{}, mccp2StartSequence...), mccp2StartSequence...)) {
+		t.Fatalf("output did not begin with the MCCP2 start sequences: %v", data)
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(data[2*len(mccp2StartSequence):]))
+	if err != nil {
+		t.Fatalf("compressed stream could not be opened: %v", err)
+	}
+
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil || string(decompressed) != "compressed\r\n" {
+		t.Errorf("decompressed %q (%v), expected %q", decompressed, err, "compressed\r\n")
+	}
+}
+
+func TestGMCPSupportsSet(t *testing.T) {
+	tests := []struct {
+		payload  string
+		module   string
+		expected bool
+	}{
+		{`["Char 1", "Room 1"]`, "char", true},
+		{`["Char 1", "Room 1"]`, "comm", false},
+		{`[""]`, "char", false},
+		{`[" "]`, "char", false},
+		{`["", " ", "Room 1"]`, "room", true},
+	}
+
+	for _, test := range tests {
+		client := newTestTelnetClient()
+		client.handleGMCP([]byte("Core.Supports.Set " + test.payload))
+
+		if client.gmcp.supports == nil {
+			t.Errorf("expected %s to set the supported modules", test.payload)
+			continue
+		}
+
+		if supported := client.gmcp.supports[test.module]; supported != test.expected {
+			t.Errorf("expected support for %s to be %v after %s, got %v", test.module, test.expected, test.payload, supported)
+		}
+	}
+}
+
+func TestSendGMCPReportsWhetherSent(t *testing.T) {
+	client := newTestTelnetClient()
+
+	if sent, _ := client.sendGMCP("Char.Vitals", nil); sent {
+		t.Errorf("expected nothing to be sent before GMCP was negotiated")
+	}
+
+	client.telnetOptions[TelnetGMCP] = &TelnetOptionState{Us: TelnetOptionYes}
+
+	if sent, _ := client.sendGMCP("Char.Vitals", nil); !sent {
+		t.Errorf("expected a message to be sent once GMCP was negotiated")
+	}
+
+	drainTestTelnetClient(client)
+	client.handleGMCP([]byte(`Core.Supports.Set ["Room 1"]`))
+
+	if sent, _ := client.sendGMCP("Char.Vitals", nil); sent {
+		t.Errorf("expected nothing to be sent for a module the client doesn't support")
+	}
+}
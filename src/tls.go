@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+/*
+ * Holds the certificate presented to TLS clients so that it can be swapped out
+ * (e.g. after a renewal) on SIGHUP without dropping the listener or any sessions.
+ */
+type CertificateReloader struct {
+	mutex           sync.RWMutex
+	certificate     *tls.Certificate
+	certificateFile string
+	keyFile         string
+}
+
+func NewCertificateReloader(certificateFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{
+		certificateFile: certificateFile,
+		keyFile:         keyFile,
+	}
+
+	err := reloader.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func (reloader *CertificateReloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(reloader.certificateFile, reloader.keyFile)
+	if err != nil {
+		return err
+	}
+
+	reloader.mutex.Lock()
+	reloader.certificate = &certificate
+	reloader.mutex.Unlock()
+
+	return nil
+}
+
+func (reloader *CertificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+
+	return reloader.certificate, nil
+}
+
+/* Reload the certificate whenever the process receives SIGHUP */
+func (reloader *CertificateReloader) watchSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		err := reloader.Reload()
+		if err != nil {
+			log.Printf("Failed to reload TLS certificate, keeping the previous one: %v\r\n", err)
+			continue
+		}
+
+		log.Printf("Reloaded TLS certificate from %s.\r\n", reloader.certificateFile)
+	}
+}
+
+func (client *Client) isTLS() bool {
+	_, ok := client.conn.(*tls.Conn)
+	return ok
+}
+
+/* Accept TLS sessions on a second port, handing them to the same client path as plain telnet */
+func (game *Game) listenTLS() {
+	reloader, err := NewCertificateReloader(Config.TLSConfiguration.CertificateFile, Config.TLSConfiguration.KeyFile)
+	if err != nil {
+		log.Printf("Unable to load TLS certificate, TLS listener disabled: %v\r\n", err)
+		return
+	}
+
+	go reloader.watchSignals()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", Config.TLSConfiguration.Port))
+	if err != nil {
+		log.Printf("Unable to start TLS listener: %v\r\n", err)
+		return
+	}
+
+	app := tls.NewListener(listener, &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	})
+
+	log.Printf("Accepting TLS connections on port %d.\r\n", Config.TLSConfiguration.Port)
+
+	for {
+		conn, err := app.Accept()
+		if err != nil {
+			log.Printf("Failed to accept TLS connection: %v\r\n", err)
+			continue
+		}
+
+		go game.handleConnection(conn)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"time"
+)
+
+/* Wait states are counted in pulses of the game loop */
+const PulsesPerSecond = 4
+const PulseDuration = time.Second / PulsesPerSecond
+
+/* Lines of input a lagged player may have waiting before more are turned away */
+const MaxQueuedCommands = 20
+
+type QueuedCommand struct {
+	input string
+
+	/* Already passed through alias expansion, so run as the command it is */
+	expanded bool
+}
+
+/*
+ * Lag a character for a number of pulses, as skills and spells do after they are used.
+ * Like a ROM WAIT_STATE, this never shortens a wait already in progress.
+ */
+func (ch *Character) SetWait(pulses int) {
+	if pulses > ch.Wait {
+		ch.Wait = pulses
+	}
+}
+
+/* Hold a line of input until the character's wait state has passed */
+func (ch *Character) queueInput(input string) {
+	if len(ch.inputQueue) >= MaxQueuedCommands {
+		ch.Send("{RYou have too many commands waiting; that one was discarded.{x\r\n")
+		return
+	}
+
+	ch.inputQueue = append(ch.inputQueue, QueuedCommand{input: input})
+}
+
+/* Put commands from a partly run alias back at the front of the queue, ahead of later input */
+func (ch *Character) requeueCommands(commands []string) {
+	requeued := make([]QueuedCommand, 0, len(commands)+len(ch.inputQueue))
+
+	for _, command := range commands {
+		requeued = append(requeued, QueuedCommand{input: command, expanded: true})
+	}
+
+	ch.inputQueue = append(requeued, ch.inputQueue...)
+}
+
+/* Run queued input until the queue is empty or a command lags the character again */
+func (ch *Character) drainInputQueue() {
+	for ch.Wait == 0 && len(ch.inputQueue) > 0 {
+		if ch.Client == nil || ch.Client.ConnectionState != ConnectionStatePlaying {
+			ch.inputQueue = nil
+			return
+		}
+
+		next := ch.inputQueue[0]
+		ch.inputQueue = ch.inputQueue[1:]
+
+		/* Things may have changed while the line waited, like being stunned or opening an editor */
+		if !ch.interpretReady(next.input) {
+			continue
+		}
+
+		if next.expanded {
+			ch.interpretCommands([]string{next.input})
+			continue
+		}
+
+		ch.interpretLine(next.input)
+	}
+}
+
+/* Count down every wait state by a pulse, then run input that was held behind one */
+func (game *Game) waitUpdate() {
+	ready := make([]*Character, 0)
+
+	for iter := game.Characters.Head; iter != nil; iter = iter.Next {
+		ch := iter.Value.(*Character)
+
+		if ch.Wait > 0 {
+			ch.Wait--
+		}
+
+		if ch.Wait == 0 && len(ch.inputQueue) > 0 {
+			ready = append(ready, ch)
+		}
+	}
+
+	/* Commands can move or extract characters, so run them outside the walk of the list */
+	for _, ch := range ready {
+		ch.drainInputQueue()
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import "testing"
+
+func TestSetWaitNeverShortens(t *testing.T) {
+	ch := NewCharacter()
+
+	ch.SetWait(8)
+	ch.SetWait(2)
+
+	if ch.Wait != 8 {
+		t.Errorf("expected a wait of 8 pulses, got %d", ch.Wait)
+	}
+}
+
+func TestRequeuedCommandsRunBeforeLaterInput(t *testing.T) {
+	ch := NewCharacter()
+
+	ch.queueInput("score")
+	ch.requeueCommands([]string{"kick orc", "flee"})
+
+	expected := []QueuedCommand{
+		{input: "kick orc", expanded: true},
+		{input: "flee", expanded: true},
+		{input: "score", expanded: false},
+	}
+
+	if len(ch.inputQueue) != len(expected) {
+		t.Fatalf("expected %d queued commands, got %d", len(expected), len(ch.inputQueue))
+	}
+
+	for index, command := range expected {
+		if ch.inputQueue[index] != command {
+			t.Errorf("queued command %d = %+v, expected %+v", index, ch.inputQueue[index], command)
+		}
+	}
+}
+
+func TestParalysedCharacterDropsQueuedInput(t *testing.T) {
+	var ran bool = false
+
+	CommandTable["zzflee"] = Command{Name: "zzflee", CmdFunc: func(ch *Character, arguments string) {
+		ran = true
+	}}
+	defer delete(CommandTable, "zzflee")
+
+	ch := NewCharacter()
+	ch.Client = &Client{ConnectionState: ConnectionStatePlaying}
+	ch.Affected |= AFFECT_PARALYSIS
+
+	ch.queueInput("zzflee")
+	ch.drainInputQueue()
+
+	if ran {
+		t.Errorf("expected a paralysed character not to run queued input")
+	}
+
+	if len(ch.inputQueue) != 0 {
+		t.Errorf("expected the queued line to be used up, %d remain", len(ch.inputQueue))
+	}
+
+	ch.Affected &= ^AFFECT_PARALYSIS
+
+	ch.queueInput("zzflee")
+	ch.drainInputQueue()
+
+	if !ran {
+		t.Errorf("expected queued input to run once the character could move")
+	}
+}
@@ -222,5 +222,12 @@ func (game *Game) handleWebhooks() {
 		game.webhookMessage <- keyParam
 	})
 
+	http.HandleFunc("/ws", game.handleWebSocket)
+
+	/* Serve the browser client, if one is configured */
+	if Config.WebConfiguration.StaticDirectory != "" {
+		http.Handle("/", http.FileServer(http.Dir(Config.WebConfiguration.StaticDirectory)))
+	}
+
 	http.ListenAndServe(":9000", nil)
 }
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2021 James Skarzinskas.
+ * All rights reserved.
+ * See LICENSE.txt in project root for license information.
+ * Authors:
+ *     James Skarzinskas <james@jskarzin.org>
+ */
+package main
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/* A frame is one line of input, so is held to the telnet line limit plus room for a line ending */
+const WebSocketMaxMessageSize = TelnetMaxLineLength + 2
+
+var webSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+/*
+ * WebSocketConn adapts a browser WebSocket to the net.Conn the client pumps expect.
+ * Each inbound text frame is one line of input; outbound writes become text frames,
+ * optionally with ANSI colour converted to HTML spans for a plain browser page.
+ */
+type WebSocketConn struct {
+	ws      *websocket.Conn
+	pending []byte
+	html    *AnsiHTMLConverter
+}
+
+func (conn *WebSocketConn) Read(b []byte) (int, error) {
+	for len(conn.pending) == 0 {
+		messageType, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				return 0, io.EOF
+			}
+
+			return 0, err
+		}
+
+		conn.pending = data
+		if messageType == websocket.TextMessage {
+			conn.pending = append(conn.pending, '\n')
+		}
+	}
+
+	n := copy(b, conn.pending)
+	conn.pending = conn.pending[n:]
+
+	return n, nil
+}
+
+func (conn *WebSocketConn) Write(b []byte) (int, error) {
+	var payload []byte = b
+
+	if conn.html != nil {
+		payload = conn.html.Convert(b)
+	}
+
+	err := conn.ws.WriteMessage(websocket.TextMessage, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (conn *WebSocketConn) Close() error {
+	return conn.ws.Close()
+}
+
+func (conn *WebSocketConn) LocalAddr() net.Addr {
+	return conn.ws.LocalAddr()
+}
+
+func (conn *WebSocketConn) RemoteAddr() net.Addr {
+	return conn.ws.RemoteAddr()
+}
+
+func (conn *WebSocketConn) SetDeadline(t time.Time) error {
+	err := conn.ws.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+
+	return conn.ws.SetWriteDeadline(t)
+}
+
+func (conn *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return conn.ws.SetReadDeadline(t)
+}
+
+func (conn *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return conn.ws.SetWriteDeadline(t)
+}
+
+/* Browser clients don't speak telnet, so no option negotiation is attempted with them */
+func (client *Client) isWebSocket() bool {
+	_, ok := client.conn.(*WebSocketConn)
+	return ok
+}
+
+/* Upgrade an HTTP request and hand the connection to the same path as a telnet client */
+func (game *Game) handleWebSocket(w http.ResponseWriter, req *http.Request) {
+	ws, err := webSocketUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade WebSocket connection: %v\r\n", err)
+		return
+	}
+
+	ws.SetReadLimit(WebSocketMaxMessageSize)
+
+	conn := &WebSocketConn{ws: ws}
+	if req.URL.Query().Get("format") == "html" {
+		conn.html = NewAnsiHTMLConverter()
+	}
+
+	log.Printf("Accepted WebSocket connection from %s.\r\n", ws.RemoteAddr().String())
+	game.handleConnection(conn)
+}